@@ -0,0 +1,44 @@
+package ownet
+
+import (
+	"errors"
+	"strings"
+)
+
+// owserver file listing aliases, one "id=alias" per line
+const aliasList = "/settings/alias/list"
+
+// Get alias of the device, as set in owserver alias list.
+// Returns alias, empty if no alias is set, and error if any.
+func (ow *OW) Alias(device string) (string, error) {
+	v, err := ow.GetAttr(device, "alias")
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// Get alias of the device. See OW.Alias.
+func (d *Device) Alias() (string, error) {
+	return d.ow.Alias(d.path())
+}
+
+// Get identifier of device with alias, as set in owserver alias list.
+// Returns device identifier and error if any, ErrNotFound if no device has
+// the alias.
+func (ow *OW) ResolveAlias(alias string) (string, error) {
+	list, err := ow.ReadString(aliasList)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(list, "\n") {
+		id, name, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && strings.TrimSpace(name) == alias {
+			return strings.TrimSpace(id), nil
+		}
+	}
+	return "", opError("resolve alias", alias, ErrNotFound)
+}
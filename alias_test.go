@@ -0,0 +1,28 @@
+package ownet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	s := newTestServer(t)
+	s.mu.Lock()
+	s.files["/28.A1B2C3000000/alias"] = "outdoor_temp"
+	s.files[aliasList] = "28.A1B2C3000000=outdoor_temp\n10.000000000001=indoor_temp\n"
+	s.mu.Unlock()
+	ow := New(s.addr())
+
+	if a, err := ow.Alias("28.A1B2C3000000"); err != nil || a != "outdoor_temp" {
+		t.Errorf("Alias: got %q, %v", a, err)
+	}
+	if a, err := ow.Alias("3A.BEE71B000000"); err != nil || a != "" {
+		t.Errorf("no alias: got %q, %v", a, err)
+	}
+	if id, err := ow.ResolveAlias("indoor_temp"); err != nil || id != "10.000000000001" {
+		t.Errorf("ResolveAlias: got %q, %v", id, err)
+	}
+	if _, err := ow.ResolveAlias("attic"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown alias: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,76 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+)
+
+// Result of reading a single path in a batch
+type Result struct {
+	Path string
+	Data []byte
+	Err  error
+}
+
+// Read complete values of owserver files at paths over a single connection.
+// Results are in the same order as paths. Error reading a path is recorded in
+// its result and does not abort the batch, except for connection failure,
+// which is recorded for all remaining paths and returned.
+func (ow *OW) ReadBatch(paths []string) (res []Result, err error) {
+	return ow.readBatch(context.Background(), paths)
+}
+
+// Same as ReadBatch, but requests are aborted when ctx is done.
+func (ow *OW) readBatch(ctx context.Context, paths []string) (res []Result, err error) {
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer ow.release(c)
+	c.hold = true
+
+	res = make([]Result, len(paths))
+	for i, path := range paths {
+		res[i].Path = path
+		if err != nil {
+			res[i].Err = err
+			continue
+		}
+		res[i].Data, res[i].Err = c.readWhole(ctx, path, 0)
+		if errors.Is(res[i].Err, ErrConnection) {
+			err = res[i].Err
+		}
+	}
+	return
+}
+
+// Write of data to a single path in a batch
+type WriteReq struct {
+	Path   string
+	Offset int
+	Data   []byte
+}
+
+// Write to owserver files over a single connection, in order, minimizing time
+// skew between writes, e.g. to set several outputs together. Writes are not
+// atomic: failure of a write is recorded and the batch continues.
+// Returns errors of writes, nil for those that succeeded, in the same order
+// as writes.
+func (ow *OW) WriteBatch(writes []WriteReq) []error {
+	errs := make([]error, len(writes))
+	ctx := context.Background()
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	defer ow.release(c)
+	c.hold = true
+
+	for i, w := range writes {
+		errs[i] = c.write(ctx, w.Path, w.Offset, w.Data)
+	}
+	return errs
+}
@@ -0,0 +1,79 @@
+package ownet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type":  "DS18B20",
+		"/3A.000000000002/PIO.A": "1",
+	})
+	ow := New(s.addr())
+
+	paths := []string{"/28.000000000001/type", "/28.000000000001/missing", "/3A.000000000002/PIO.A"}
+	res, err := ow.ReadBatch(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range res {
+		if r.Path != paths[i] {
+			t.Errorf("result %d: path %q, want %q", i, r.Path, paths[i])
+		}
+	}
+	if string(res[0].Data) != "DS18B20" || res[0].Err != nil {
+		t.Errorf("result 0: got %q, %v", res[0].Data, res[0].Err)
+	}
+	if !errors.Is(res[1].Err, ErrNotFound) {
+		t.Errorf("result 1: got error %v, want not found", res[1].Err)
+	}
+	if string(res[2].Data) != "1" || res[2].Err != nil {
+		t.Errorf("result 2: got %q, %v", res[2].Data, res[2].Err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	errs := ow.WriteBatch([]WriteReq{
+		{Path: "/3A.BEE71B000000/PIO.A", Data: []byte("1")},
+		{Path: "/3A.000000000000/PIO.A", Data: []byte("1")},
+		{Path: "/3A.BEE71B000000/PIO.B", Data: []byte("1")},
+	})
+	if len(errs) != 3 || errs[0] != nil || !errors.Is(errs[1], ErrNotFound) || errs[2] != nil {
+		t.Errorf("got %v", errs)
+	}
+	if a, b := s.file("/3A.BEE71B000000/PIO.A"), s.file("/3A.BEE71B000000/PIO.B"); a != "1" || b != "1" {
+		t.Errorf("got PIO.A=%q PIO.B=%q", a, b)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestGetAttrs(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/26.000000000001/temperature": "21.5",
+		"/26.000000000001/VDD":         "5.01",
+		"/26.000000000001/VAD":         "2.3",
+	})
+	ow := New(s.addr())
+
+	attrs, err := ow.GetAttrs("26.000000000001", "temperature", "VDD", "humidity")
+	want := map[string]string{"temperature": "21.5", "VDD": "5.01"}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("got %v, want %v", attrs, want)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing attribute: got %v, want ErrNotFound", err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
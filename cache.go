@@ -0,0 +1,47 @@
+package ownet
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache of devices present on the bus, refreshed with ListDevices when it
+// expires. Safe for concurrent use: while it is being refreshed, other
+// callers wait for the result instead of scanning the bus themselves.
+type DeviceCache struct {
+	ow  *OW
+	ttl time.Duration
+
+	mu      sync.Mutex
+	devs    []string
+	expires time.Time
+}
+
+// Get cache of devices present on the bus, which serves the last ListDevices
+// result for ttl.
+func (ow *OW) CachedDevices(ttl time.Duration) *DeviceCache {
+	return &DeviceCache{ow: ow, ttl: ttl}
+}
+
+// Get list of present devices, from the cache unless it expired.
+// Returns array of device identifiers and error if any. Failed refresh is
+// not cached.
+func (dc *DeviceCache) Devices() ([]string, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.devs == nil || !time.Now().Before(dc.expires) {
+		devs, err := dc.ow.ListDevices()
+		if err != nil {
+			return nil, err
+		}
+		dc.devs, dc.expires = devs, time.Now().Add(dc.ttl)
+	}
+	return append([]string(nil), dc.devs...), nil
+}
+
+// Invalidate the cache, so that the next call to Devices scans the bus.
+func (dc *DeviceCache) ForceRefresh() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.devs = nil
+}
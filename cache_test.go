@@ -0,0 +1,59 @@
+package ownet
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Count directory listing requests received by s.
+func dirCount(s *fakeServer) (n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, hdr := range s.requests {
+		if hdr.Type == MsgDirAll {
+			n++
+		}
+	}
+	return
+}
+
+func TestDeviceCache(t *testing.T) {
+	s := newTestServer(t)
+	s.delay = 10 * time.Millisecond
+	dc := New(s.addr()).CachedDevices(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devs, err := dc.Devices()
+			want := []string{"28.A1B2C3000000", "3A.BEE71B000000"}
+			if err != nil || !reflect.DeepEqual(devs, want) {
+				t.Errorf("got %q, %v", devs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := dirCount(s); n != 1 {
+		t.Errorf("scanned bus %d times, want 1", n)
+	}
+
+	dc.ForceRefresh()
+	if _, err := dc.Devices(); err != nil {
+		t.Fatal(err)
+	}
+	if n := dirCount(s); n != 2 {
+		t.Errorf("after refresh: scanned bus %d times, want 2", n)
+	}
+
+	dc.mu.Lock()
+	dc.expires = time.Now()
+	dc.mu.Unlock()
+	dc.Devices()
+	if n := dirCount(s); n != 3 {
+		t.Errorf("after expiry: scanned bus %d times, want 3", n)
+	}
+}
@@ -0,0 +1,615 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Connection to owserver, along with client settings in effect for the
+// operation currently using it.
+type conn struct {
+	net.Conn // nil when not connected
+	ow       *OW
+	settings
+	persist bool            // owserver agreed to keep connection open
+	hold    bool            // keep connection open between requests of a batch
+	gen     int             // pool generation the connection belongs to
+	buf     []byte          // scratch buffer for serializing requests
+	hdrbuf  [headerLen]byte // scratch buffer for parsing response headers
+}
+
+func (c *conn) close() {
+	if c.Conn != nil {
+		c.Conn.Close()
+		c.Conn = nil
+	}
+	c.persist = false
+}
+
+// Log wire traffic with logger set by WithLogger, if any.
+func (c *conn) logf(format string, args ...interface{}) {
+	if c.ow.logger != nil {
+		c.ow.logger.Printf(format, args...)
+	}
+}
+
+// Point in the past used to interrupt blocked I/O
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Apply deadline of ctx, or the request timeout if it is earlier, to the
+// connection and interrupt pending I/O when ctx is done. Returned function
+// must be called when I/O is finished.
+func (c *conn) watch(ctx context.Context) (stop func()) {
+	conn := c.Conn
+	deadline, ok := ctx.Deadline()
+	if c.timeout > 0 {
+		if d := time.Now().Add(c.timeout); !ok || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	conn.SetDeadline(deadline)
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(aLongTimeAgo)
+		case <-done:
+		}
+		close(finished)
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// Send request and read response into ret, dialing owserver if needed.
+// Connection is closed afterwards unless owserver agreed to keep it open.
+// If dialing, sending request or reading response fails, request is retried
+// on a fresh connection up to the configured number of retries, except for
+// write requests that were already sent, unless it was over a reused
+// connection that owserver turned out to have closed.
+// owserver keeps no state for a connection besides persistence, which is
+// requested in every request along with all other flags, so re-dialed
+// connection behaves the same as the one it replaces.
+// When ctx is done, connection is dropped so that no partially consumed
+// message is left on it, and error wrapping ctx.Err() is returned.
+func (c *conn) request(ctx context.Context, hdr Header, payload, ret []byte) (rhdr Header, n int, err error) {
+	if ctx.Err() != nil {
+		return rhdr, 0, contextError(ctx)
+	}
+	keep := c.persistent || c.hold
+	if keep {
+		hdr.Flags |= flagPersistence
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := c.retryWait(ctx, attempt); err != nil {
+				return rhdr, 0, err
+			}
+		}
+		reused := c.Conn != nil
+		if c.Conn == nil {
+			if c.Conn, err = c.ow.dial(ctx, c.timeout); err != nil {
+				c.Conn = nil
+				if ctx.Err() != nil {
+					return rhdr, 0, contextError(ctx)
+				}
+				if attempt < c.retries {
+					continue
+				}
+				c.ow.stats.errors.Add(1)
+				return rhdr, 0, &connError{err}
+			}
+			c.ow.stats.dials.Add(1)
+		} else {
+			c.ow.stats.reuses.Add(1)
+		}
+		stop := c.watch(ctx)
+		sent := false
+		if err = c.msgWrite(hdr, payload); err == nil {
+			sent = true
+			rhdr, n, err = c.msgRead(ret)
+		}
+		stop()
+		if err != nil {
+			c.close()
+			if ctx.Err() != nil {
+				return rhdr, 0, contextError(ctx)
+			}
+			// owserver may have applied a write that was sent before the
+			// connection failed, so only retry writes that were not sent,
+			// or found reused connection closed by owserver while idle
+			applied := sent && !(reused && rhdr == Header{} && isStale(err))
+			if attempt < c.retries && !(applied && hdr.Type == MsgWrite) {
+				continue
+			}
+			c.ow.stats.errors.Add(1)
+			return rhdr, 0, &connError{err}
+		}
+		if responseError(rhdr) != nil {
+			c.ow.stats.errors.Add(1)
+		}
+		c.negotiate(keep, rhdr, true)
+		return
+	}
+}
+
+// Tell whether err of a request on a reused connection means that owserver
+// had closed the connection before it was used: it is closed or reset
+// without responding. Timeouts do not qualify, as owserver may be just slow.
+func isStale(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// Record whether owserver agreed in response rhdr to keep connection open,
+// if that was requested, and close connection unless it did and the
+// connection is reusable.
+func (c *conn) negotiate(keep bool, rhdr Header, reusable bool) {
+	granted := keep && rhdr.Flags&flagPersistence != 0
+	// connection held for a batch does not make persistent mode
+	c.ow.granted.Store(granted && c.persistent)
+	c.persist = granted && reusable
+	if !c.persist {
+		c.close()
+	}
+}
+
+// Wait before retry attempt as set by WithRetry: base delay doubled for each
+// attempt after the first retry, capped by maximum delay, and reduced by
+// random jitter of up to a half. Returns error if ctx is done meanwhile.
+func (c *conn) retryWait(ctx context.Context, attempt int) error {
+	if c.retryDelay <= 0 {
+		return nil
+	}
+	d := c.retryDelay
+	for i := 1; i < attempt && (c.retryMaxDelay <= 0 || d < c.retryMaxDelay); i++ {
+		d *= 2
+	}
+	if c.retryMaxDelay > 0 && d > c.retryMaxDelay {
+		d = c.retryMaxDelay
+	}
+	d -= time.Duration(rand.Int63n(int64(d/2) + 1))
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+}
+
+func contextError(ctx context.Context) error {
+	return fmt.Errorf("ownet: %w", ctx.Err())
+}
+
+// Read response header and payload. Keepalive headers with negative Payload,
+// which owserver sends while response is being prepared, are skipped.
+// Payload that does not fit into payload buffer is read up to its length
+// and the rest is discarded, keeping connection usable for further requests;
+// returned hdr.Payload then exceeds n, which callers must not ignore.
+func (c *conn) msgRead(payload []byte) (hdr Header, n int, err error) {
+	for {
+		if _, err = io.ReadFull(c.Conn, c.hdrbuf[:]); err != nil {
+			return
+		}
+		c.ow.stats.received.Add(headerLen)
+		hdr.parse(c.hdrbuf[:])
+		c.logf("<- %+v", hdr)
+		if hdr.Payload >= 0 {
+			break
+		}
+	}
+	last := hdr
+	c.ow.last.Store(&last)
+	if prev := c.ow.version.Swap(hdr.Version); hdr.Version > ProtocolVersion && prev != hdr.Version {
+		c.logf("owserver speaks protocol version %d, newer than supported %d", hdr.Version, ProtocolVersion)
+	}
+	size := int(hdr.Payload)
+	if size > len(payload) {
+		size = len(payload)
+	}
+	n, err = io.ReadFull(c.Conn, payload[:size])
+	c.ow.stats.received.Add(uint64(n))
+	if err != nil {
+		return
+	}
+	if rest := int64(hdr.Payload) - int64(size); rest > 0 {
+		var m int64
+		m, err = io.CopyN(io.Discard, c.Conn, rest)
+		c.ow.stats.received.Add(uint64(m))
+	}
+	c.logf("<- n:%v payload:%q", n, payload[:n])
+	return
+}
+
+func (c *conn) msgWrite(hdr Header, payload []byte) (err error) {
+	c.logf("-> %+v", hdr)
+	c.logf("-> payload: %q", payload)
+	c.buf = hdr.append(c.buf[:0])
+	c.buf = append(c.buf, payload...)
+	n, err := c.Conn.Write(c.buf)
+	c.ow.stats.sent.Add(uint64(n))
+	c.ow.stats.requests.Add(1)
+	return
+}
+
+// Directory listing request of type msgType, either MsgDirAll or
+// MsgDirAllSlash. The latter appends slash to items that are directories.
+func (c *conn) dir(ctx context.Context, path string, msgType int32) (items []string, err error) {
+	defer func() { err = opError("dir", path, err) }()
+	hdr, data, err := c.fetch(ctx, path, msgType)
+	if err != nil {
+		return
+	}
+	if err = responseError(hdr); err != nil {
+		return nil, err
+	}
+	return splitDir(data), nil
+}
+
+// Request of type MsgGet or MsgGetSlash, to which owserver responds with
+// directory listing if path is a directory, like to MsgDirAll or
+// MsgDirAllSlash respectively, and with value otherwise, like to MsgRead.
+func (c *conn) get(ctx context.Context, path string, msgType int32) (data []byte, err error) {
+	defer func() { err = opError("get", path, err) }()
+	_, data, err = c.fetch(ctx, path, msgType)
+	return
+}
+
+// Send request of type msgType for whole contents of path, either a listing
+// or a value. If response does not fit into the buffer, request is repeated
+// with a buffer large enough for it, so that the contents are complete.
+func (c *conn) fetch(ctx context.Context, path string, msgType int32) (hdr Header, data []byte, err error) {
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	size := 4096
+	for {
+		ret := make([]byte, size, size)
+		hdr = Header{
+			Version: 0,
+			Payload: int32(len(path) + 1),
+			Type:    msgType,
+			Flags:   c.sg,
+			Size:    int32(len(ret)),
+		}
+		var n int
+		hdr, n, err = c.request(ctx, hdr, append([]byte(path), 0), ret)
+		if err != nil {
+			return
+		}
+		if err = responseError(hdr); err != nil {
+			return hdr, nil, err
+		}
+		// owserver either sends the whole response, of which only the part
+		// fitting into the buffer was read, or truncates it to the buffer
+		switch {
+		case int(hdr.Payload) > n:
+			size = int(hdr.Payload)
+		case n == len(ret):
+			size *= 2
+		default:
+			return hdr, ret[:n], nil
+		}
+		if size > maxValueSize {
+			return hdr, nil, fmt.Errorf("ownet: response exceeds %d bytes", maxValueSize)
+		}
+	}
+}
+
+// Directory listing request of type MsgDir, to which owserver responds with
+// a message per item, terminated by a message with empty payload. Each item
+// is passed to fn as soon as it arrives; if fn returns error, listing is
+// aborted and the error returned. Request is retried on connection failure
+// only until the first item is received.
+func (c *conn) dirEach(ctx context.Context, path string, fn func(item string) error) (err error) {
+	defer func() { err = opError("dir", path, err) }()
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	if ctx.Err() != nil {
+		return contextError(ctx)
+	}
+	keep := c.persistent || c.hold
+	hdr := Header{
+		Version: 0,
+		Payload: int32(len(path) + 1),
+		Type:    MsgDir,
+		Flags:   c.sg,
+	}
+	if keep {
+		hdr.Flags |= flagPersistence
+	}
+	ret := make([]byte, 4096, 4096)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := c.retryWait(ctx, attempt); err != nil {
+				return err
+			}
+		}
+		if c.Conn == nil {
+			if c.Conn, err = c.ow.dial(ctx, c.timeout); err != nil {
+				c.Conn = nil
+				if ctx.Err() != nil {
+					return contextError(ctx)
+				}
+				if attempt < c.retries {
+					continue
+				}
+				c.ow.stats.errors.Add(1)
+				return &connError{err}
+			}
+			c.ow.stats.dials.Add(1)
+		} else {
+			c.ow.stats.reuses.Add(1)
+		}
+		stop := c.watch(ctx)
+		received := false
+		var rhdr Header
+		var n int
+		ioErr := c.msgWrite(hdr, append([]byte(path), 0))
+		for ioErr == nil {
+			if rhdr, n, ioErr = c.msgRead(ret); ioErr != nil {
+				break
+			}
+			if err = responseError(rhdr); err != nil {
+				break
+			}
+			if rhdr.Payload == 0 {
+				break
+			}
+			if int(rhdr.Payload) > n {
+				err = fmt.Errorf("ownet: listing item exceeds %d bytes", len(ret))
+				break
+			}
+			received = true
+			if err = fn(strings.TrimRight(string(ret[:n]), "\x00")); err != nil {
+				break
+			}
+		}
+		stop()
+		if ioErr != nil {
+			c.close()
+			if ctx.Err() != nil {
+				return contextError(ctx)
+			}
+			if attempt < c.retries && !received {
+				continue
+			}
+			c.ow.stats.errors.Add(1)
+			return &connError{ioErr}
+		}
+		if err != nil {
+			c.ow.stats.errors.Add(1)
+		}
+		// unless owserver reported error, listing was aborted with items
+		// left unread, so connection can not be reused
+		c.negotiate(keep, rhdr, err == nil || responseError(rhdr) != nil)
+		return
+	}
+}
+
+// Check that offset and sizes of data and payload of request fit into the
+// header fields, which are signed 32-bit integers.
+func checkRange(offset, size, payload int) error {
+	switch {
+	case offset < 0 || offset > math.MaxInt32:
+		return fmt.Errorf("%w: offset %d", ErrOutOfRange, offset)
+	case size < 0 || size > math.MaxInt32:
+		return fmt.Errorf("%w: size %d", ErrOutOfRange, size)
+	case payload > math.MaxInt32:
+		return fmt.Errorf("%w: payload of %d bytes", ErrOutOfRange, payload)
+	}
+	return nil
+}
+
+// Read request with additional flags set.
+func (c *conn) read(ctx context.Context, path string, offset int, data []byte, flags int32) (n int, err error) {
+	defer func() { err = opError("read", path, err) }()
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	if err = checkRange(offset, len(data), len(path)+1); err != nil {
+		return
+	}
+	hdr := Header{
+		Version: 0,
+		Payload: int32(len(path) + 1),
+		Type:    MsgRead,
+		Flags:   c.sg | flags,
+		Size:    int32(len(data)),
+		Offset:  int32(offset),
+	}
+	hdr, n, err = c.request(ctx, hdr, append([]byte(path), 0), data)
+	if err != nil {
+		return
+	}
+	if err = responseError(hdr); err != nil {
+		return
+	}
+	if int(hdr.Payload) > n {
+		err = &BufferTooSmallError{Size: int(hdr.Payload)}
+	}
+	return
+}
+
+// Maximum size of value read as a whole
+const maxValueSize = 1 << 20
+
+// Read complete value of owserver file at path, which is expected to be size
+// bytes long, or of unknown size if size is not positive. A read filling the
+// buffer means that there may be more data, so reading continues at
+// increasing offsets with growing buffer until owserver returns less data
+// than requested or expected size is reached.
+func (c *conn) readWhole(ctx context.Context, path string, size int) (_ []byte, err error) {
+	defer func() { err = opError("read", path, err) }()
+	chunk := c.readSize
+	if size > chunk {
+		chunk = size
+	}
+	var out []byte
+	for {
+		if cap(out)-len(out) < chunk {
+			out = append(make([]byte, 0, len(out)+chunk), out...)
+		}
+		n, err := c.read(ctx, path, len(out), out[len(out):len(out)+chunk], 0)
+		if errors.Is(err, ErrBufferTooSmall) {
+			// chunk is full, the rest is read at the next offset
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = out[:len(out)+n]
+		if n < chunk || size > 0 && len(out) >= size {
+			return out, nil
+		}
+		if len(out) >= maxValueSize {
+			return nil, fmt.Errorf("ownet: value exceeds %d bytes", maxValueSize)
+		}
+		chunk *= 2
+	}
+}
+
+func (c *conn) write(ctx context.Context, path string, offset int, data []byte) (err error) {
+	defer func() { err = opError("write", path, err) }()
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	if len(data) == 0 {
+		return ErrEmptyWrite
+	}
+	if err = checkRange(offset, len(data), len(path)+1+len(data)); err != nil {
+		return
+	}
+	hdr := Header{
+		Version: 0,
+		Payload: int32(len(path) + 1 + len(data)),
+		Type:    MsgWrite,
+		Flags:   c.sg,
+		Size:    int32(len(data)),
+		Offset:  int32(offset),
+	}
+	hdr, _, err = c.request(ctx, hdr, append(append([]byte(path), 0), data...), nil)
+	if err != nil {
+		return
+	}
+	if err = responseError(hdr); err != nil {
+		return
+	}
+	return
+}
+
+func (c *conn) size(ctx context.Context, path string) (size int, err error) {
+	defer func() { err = opError("size", path, err) }()
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	hdr := Header{
+		Version: 0,
+		Payload: int32(len(path) + 1),
+		Type:    MsgSize,
+		Flags:   c.sg,
+	}
+	hdr, _, err = c.request(ctx, hdr, append([]byte(path), 0), nil)
+	if err != nil {
+		return
+	}
+	if err = responseError(hdr); err != nil {
+		return
+	}
+	return int(hdr.Type), nil
+}
+
+func (c *conn) presence(ctx context.Context, path string) (present bool, err error) {
+	defer func() { err = opError("presence", path, err) }()
+	clean, err := CleanPath(path)
+	if err != nil {
+		return
+	}
+	path = clean
+	hdr := Header{
+		Version: 0,
+		Payload: int32(len(path) + 1),
+		Type:    MsgPresence,
+		Flags:   c.sg,
+	}
+	hdr, _, err = c.request(ctx, hdr, append([]byte(path), 0), nil)
+	if err != nil {
+		return
+	}
+	switch err = responseError(hdr); {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Request of arbitrary type, see OW.RawRequest.
+func (c *conn) raw(ctx context.Context, msgType int32, path string, data []byte, offset, size int) (hdr Header, ret []byte, err error) {
+	defer func() { err = opError("request", path, err) }()
+	if err = checkRange(offset, size, len(path)+1+len(data)); err != nil {
+		return
+	}
+	hdr = Header{
+		Version: 0,
+		Payload: int32(len(path) + 1 + len(data)),
+		Type:    msgType,
+		Flags:   c.sg,
+		Size:    int32(size),
+		Offset:  int32(offset),
+	}
+	ret = make([]byte, size, size)
+	hdr, n, err := c.request(ctx, hdr, append(append([]byte(path), 0), data...), ret)
+	if err != nil {
+		return hdr, nil, err
+	}
+	if err = responseError(hdr); err != nil {
+		return hdr, nil, err
+	}
+	return hdr, ret[:n], nil
+}
+
+func (c *conn) nop(ctx context.Context) (err error) {
+	hdr := Header{
+		Version: 0,
+		Payload: 0,
+		Type:    MsgNop,
+		Flags:   c.sg,
+	}
+	hdr, _, err = c.request(ctx, hdr, nil, nil)
+	if err != nil {
+		return
+	}
+	if err = responseError(hdr); err != nil {
+		return
+	}
+	return
+}
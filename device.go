@@ -0,0 +1,139 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+// Device on the bus bound to OW client it is accessed with.
+type Device struct {
+	ow     *OW
+	prefix string // virtual root the device is accessed under, if any
+	id     string
+}
+
+// Get device with identifier id accessed via ow. Device presence is not
+// checked.
+func (ow *OW) Device(id string) *Device {
+	return &Device{ow: ow, id: id}
+}
+
+// Get device identifier.
+func (d *Device) ID() string {
+	return d.id
+}
+
+// Path of the device relative to owserver root.
+func (d *Device) path() string {
+	return d.prefix + "/" + d.id
+}
+
+// Get value of attribute name of the device. See OW.GetAttr.
+func (d *Device) Attr(name string) (string, error) {
+	return d.ow.GetAttr(d.path(), name)
+}
+
+// Set value of attribute name of the device. See OW.SetAttr.
+func (d *Device) SetAttr(name, value string) error {
+	return d.ow.SetAttr(d.path(), name, value)
+}
+
+// Get type of the device. See OW.GetType.
+func (d *Device) Type() (string, error) {
+	return d.ow.GetType(d.path())
+}
+
+// Get temperature of the device. See OW.Temperature.
+func (d *Device) Temperature() (float64, error) {
+	return d.ow.Temperature(d.path())
+}
+
+// Get values of all attributes of the device, keyed by attribute name.
+// Subdirectories of the device are skipped. Failure to read an attribute
+// (e.g. a write-only one) does not abort the call: values that could be
+// read are returned along with error listing attributes that failed.
+func (ow *OW) GetAllAttrs(device string) (map[string]string, error) {
+	items, err := ow.dir(context.Background(), "/"+device, MsgDirAllSlash)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(map[string]string)
+	var errs []error
+	for _, item := range items {
+		if strings.HasSuffix(item, "/") {
+			continue
+		}
+		name := path.Base(item)
+		v, err := ow.GetAttr(device, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		attrs[name] = v
+	}
+	return attrs, errors.Join(errs...)
+}
+
+// Get values of the named attributes of the device, read over a single
+// connection, keyed by attribute name. Failure to read an attribute does not
+// abort the call: values that could be read are returned along with error
+// listing attributes that failed.
+func (ow *OW) GetAttrs(device string, attrs ...string) (map[string]string, error) {
+	paths := make([]string, len(attrs))
+	for i, attr := range attrs {
+		paths[i] = "/" + device + "/" + attr
+	}
+	res, err := ow.ReadBatch(paths)
+	if res == nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	var errs []error
+	for i, r := range res {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		values[attrs[i]] = strings.TrimRight(string(r.Data), "\x00")
+	}
+	return values, errors.Join(errs...)
+}
+
+// Device found by ListDevicesDetailed
+type DeviceInfo struct {
+	ID     string
+	Family byte
+	Type   string // device type, as returned by GetType
+	Err    error  // error reading device type, if any
+}
+
+// Get list of present devices along with their types, which are read over
+// a single connection. Failure to read type of a device does not abort the
+// call, but is recorded in its entry. Connection failure is recorded in all
+// entries not read yet, and returned.
+// Returns array of devices and error if any.
+func (ow *OW) ListDevicesDetailed() ([]DeviceInfo, error) {
+	devs, err := ow.ListDevices()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(devs))
+	for i, dev := range devs {
+		paths[i] = "/" + dev + "/type"
+	}
+	res, err := ow.ReadBatch(paths)
+	if err != nil && res == nil {
+		return nil, err
+	}
+	infos := make([]DeviceInfo, len(devs))
+	for i, dev := range devs {
+		infos[i].ID = dev
+		if id, err := ParseDeviceID(dev); err == nil {
+			infos[i].Family = id.Family
+		}
+		infos[i].Type, infos[i].Err = strings.TrimRight(string(res[i].Data), "\x00"), res[i].Err
+	}
+	return infos, err
+}
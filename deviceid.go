@@ -0,0 +1,107 @@
+package ownet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Components of 1-Wire device identifier
+type DeviceID struct {
+	Family byte    // family code, identifying device type
+	Serial [6]byte // serial number, in the order shown by owserver
+	CRC    byte    // CRC of family and serial, if present in identifier
+	Valid  bool    // CRC was present in identifier and matches
+}
+
+// Parse device identifier in any of the display formats, in either case.
+// Returns parsed identifier and error if id is not a device identifier.
+func ParseDeviceID(id string) (d DeviceID, err error) {
+	if !deviceElemRegex.MatchString(id) {
+		return d, fmt.Errorf("ownet: invalid device identifier %q", id)
+	}
+	b, err := hex.DecodeString(strings.ReplaceAll(id, ".", ""))
+	if err != nil {
+		return d, fmt.Errorf("ownet: invalid device identifier %q", id)
+	}
+	d.Family = b[0]
+	copy(d.Serial[:], b[1:7])
+	if len(b) > 7 {
+		d.CRC = b[7]
+		d.Valid = d.crc() == d.CRC
+	}
+	return d, nil
+}
+
+// Check that id is a device identifier in any of the display formats and,
+// if it includes CRC, that the CRC matches family and serial, e.g. to catch
+// typos in configuration before accessing the bus.
+// Returns nil if id is valid, error describing the problem otherwise.
+func ValidateDeviceID(id string) error {
+	d, err := ParseDeviceID(id)
+	if err != nil {
+		return err
+	}
+	if len(strings.ReplaceAll(id, ".", "")) > 14 && !d.Valid {
+		return fmt.Errorf("ownet: CRC mismatch in device identifier %q: got %02X, want %02X", id, d.CRC, d.crc())
+	}
+	return nil
+}
+
+// Compute CRC of family and serial.
+func (d DeviceID) crc() byte {
+	return crc8(append([]byte{d.Family}, d.Serial[:]...))
+}
+
+// Get identifier in canonical f.i format, like "28.A1B2C3000000".
+func (d DeviceID) String() string {
+	return fmt.Sprintf("%02X.%X", d.Family, d.Serial[:])
+}
+
+// Get name of device type of the family, or empty string if it is unknown.
+func (d DeviceID) FamilyName() string {
+	return FamilyName(d.Family)
+}
+
+// Names of well-known device families
+var familyNames = map[byte]string{
+	0x01: "DS2401",
+	0x05: "DS2405",
+	0x10: "DS18S20",
+	0x12: "DS2406",
+	0x1D: "DS2423",
+	0x1F: "DS2409",
+	0x20: "DS2450",
+	0x22: "DS1822",
+	0x23: "DS2433",
+	0x24: "DS2415",
+	0x26: "DS2438",
+	0x27: "DS2417",
+	0x28: "DS18B20",
+	0x29: "DS2408",
+	0x2D: "DS2431",
+	0x3A: "DS2413",
+	0x3B: "DS1825",
+	0x42: "DS28EA00",
+}
+
+// Get name of device type of family, like "DS18B20" for 0x28, or empty
+// string if family is unknown.
+func FamilyName(family byte) string {
+	return familyNames[family]
+}
+
+// Dallas/Maxim 1-Wire CRC8 of data
+func crc8(data []byte) (crc byte) {
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 1
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8C
+			}
+			b >>= 1
+		}
+	}
+	return
+}
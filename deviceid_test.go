@@ -0,0 +1,57 @@
+package ownet
+
+import (
+	"testing"
+)
+
+func TestParseDeviceID(t *testing.T) {
+	// 28.FF4C0D600400 with CRC 0x83
+	for _, id := range []string{
+		"28.FF4C0D600400",
+		"28ff4c0d600400",
+		"28.FF4C0D600400.83",
+		"28.FF4C0D60040083",
+		"28FF4C0D600400.83",
+		"28FF4C0D60040083",
+	} {
+		d, err := ParseDeviceID(id)
+		if err != nil {
+			t.Errorf("%s: %v", id, err)
+			continue
+		}
+		if d.Family != 0x28 || d.Serial != [6]byte{0xFF, 0x4C, 0x0D, 0x60, 0x04, 0x00} {
+			t.Errorf("%s: got %+v", id, d)
+		}
+		if s := d.String(); s != "28.FF4C0D600400" {
+			t.Errorf("%s: String() = %q", id, s)
+		}
+		if d.FamilyName() != "DS18B20" {
+			t.Errorf("%s: family name %q", id, d.FamilyName())
+		}
+		if hasCRC := len(id) > 15; d.Valid != hasCRC {
+			t.Errorf("%s: Valid = %v, want %v", id, d.Valid, hasCRC)
+		}
+	}
+
+	if d, err := ParseDeviceID("28.FF4C0D600400.84"); err != nil || d.Valid {
+		t.Errorf("wrong CRC: got %+v, %v", d, err)
+	}
+	for _, id := range []string{"", "28", "bus.0", "28.FF4C0D60040G", "/28.FF4C0D600400"} {
+		if _, err := ParseDeviceID(id); err == nil {
+			t.Errorf("%q: no error", id)
+		}
+	}
+}
+
+func TestValidateDeviceID(t *testing.T) {
+	for _, id := range []string{"28.FF4C0D600400", "28.FF4C0D600400.83", "28ff4c0d60040083"} {
+		if err := ValidateDeviceID(id); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+	for _, id := range []string{"28.FF4C0D600400.84", "28.FF4C0D6004", "bus.0"} {
+		if err := ValidateDeviceID(id); err == nil {
+			t.Errorf("%s: no error", id)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ownet
+
+import (
+	"context"
+)
+
+// Stream directory listing of path by sending MsgDir request, to which
+// owserver responds with one item at a time. Unlike Dir, listing size is not
+// limited, and items can be processed before the whole listing arrives.
+// Items are sent to the returned items channel, which is closed when listing
+// is finished; then the error, if any, is sent to the returned error channel.
+// Listing is done over a connection of its own, so caller may use ow while
+// receiving items, but must receive all of them, as the connection is held
+// until then.
+func (ow *OW) DirStream(path string) (<-chan string, <-chan error) {
+	return ow.DirStreamContext(context.Background(), path)
+}
+
+// Same as DirStream, but listing is aborted when ctx is done. Caller may
+// stop receiving items after cancelling ctx.
+func (ow *OW) DirStreamContext(ctx context.Context, path string) (<-chan string, <-chan error) {
+	items := make(chan string)
+	errc := make(chan error, 1)
+	ow.Lock()
+	c := &conn{ow: ow, settings: ow.settings}
+	ow.Unlock()
+	go func() {
+		defer close(errc)
+		defer c.close()
+		err := c.dirEach(ctx, path, func(item string) error {
+			select {
+			case items <- item:
+				return nil
+			case <-ctx.Done():
+				return contextError(ctx)
+			}
+		})
+		close(items)
+		errc <- err
+	}()
+	return items, errc
+}
+
+// Stream directory listing of path, passing items to fn.
+func (ow *OW) dirEach(ctx context.Context, path string, fn func(item string) error) (err error) {
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer ow.release(c)
+	return c.dirEach(ctx, path, fn)
+}
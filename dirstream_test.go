@@ -0,0 +1,74 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDirStream(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	want, err := ow.Dir("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, errc := ow.DirStream("/")
+	var got []string
+	for item := range items {
+		got = append(got, item)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	items, errc = ow.DirStream("/nonexistent")
+	for range items {
+		t.Error("item in nonexistent directory")
+	}
+	if err := <-errc; !errors.Is(err, ErrNotFound) {
+		t.Errorf("nonexistent directory: got %v", err)
+	}
+
+	// abandoned listing must not leave stale messages on the connection
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errc = ow.DirStreamContext(ctx, "/")
+	<-items
+	cancel()
+	<-errc
+	if _, err := ow.Dir("/"); err != nil {
+		t.Errorf("dir after aborted stream: %v", err)
+	}
+}
+
+func TestDirStreamUseWhileReceiving(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+
+	done := make(chan error, 1)
+	go func() {
+		items, errc := ow.DirStream("/")
+		for item := range items {
+			if ok, err := ow.Exists(item); err != nil || !ok {
+				t.Errorf("Exists(%s): got %v, %v", item, ok, err)
+			}
+		}
+		done <- <-errc
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+		ow.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock using ow while receiving items")
+	}
+}
@@ -0,0 +1,60 @@
+package ownet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Standard values of DS2438 battery monitor, which many humidity sensor
+// modules are built on
+type DS2438 struct {
+	Temperature float64 // temperature in configured scale
+	VDD         float64 // supply voltage, V
+	VAD         float64 // voltage at the A/D input, V
+	Humidity    float64 // relative humidity, %
+}
+
+// Attributes DS2438 humidity is exposed under, in order of preference:
+// computed for the default sensor, or for particular sensor types
+var humidityAttrs = []string{"humidity", "HIH4000/humidity", "HTM1735/humidity", "HIH3600/humidity"}
+
+// Read standard values of DS2438 device over a single connection. Humidity
+// is taken from the first of the attributes owserver computes it in,
+// depending on sensor type, that is present.
+// Returns values and error if any, matching ErrNotFound if the device has no
+// humidity attribute.
+func (ow *OW) ReadDS2438(device string) (v DS2438, err error) {
+	fields := []*float64{&v.Temperature, &v.VDD, &v.VAD}
+	paths := []string{"/" + device + "/temperature", "/" + device + "/VDD", "/" + device + "/VAD"}
+	for _, attr := range humidityAttrs {
+		paths = append(paths, "/"+device+"/"+attr)
+	}
+	res, err := ow.ReadBatch(paths)
+	if err != nil {
+		return v, err
+	}
+	for i, field := range fields {
+		if res[i].Err != nil {
+			return v, res[i].Err
+		}
+		if *field, err = parseFloat(res[i].Path, res[i].Data); err != nil {
+			return
+		}
+	}
+	for _, r := range res[len(fields):] {
+		if errors.Is(r.Err, ErrNotFound) {
+			continue
+		}
+		if r.Err != nil {
+			return v, r.Err
+		}
+		v.Humidity, err = parseFloat(r.Path, r.Data)
+		return
+	}
+	return v, fmt.Errorf("ownet: device %s has no humidity attribute: %w", device, ErrNotFound)
+}
+
+// Read standard values of DS2438 device. See OW.ReadDS2438.
+func (d *Device) ReadDS2438() (DS2438, error) {
+	return d.ow.ReadDS2438(d.path())
+}
@@ -0,0 +1,30 @@
+package ownet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadDS2438(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/26.000000000001/temperature":      "    23.40625",
+		"/26.000000000001/VDD":              "        5.01",
+		"/26.000000000001/VAD":              "        2.43",
+		"/26.000000000001/HIH4000/humidity": "     45.8652",
+		"/26.000000000002/temperature":      "       22.75",
+		"/26.000000000002/VDD":              "        4.97",
+		"/26.000000000002/VAD":              "        1.12",
+	})
+	ow := New(s.addr())
+
+	v, err := ow.ReadDS2438("26.000000000001")
+	want := DS2438{Temperature: 23.40625, VDD: 5.01, VAD: 2.43, Humidity: 45.8652}
+	if err != nil || v != want {
+		t.Errorf("got %+v, %v, want %+v", v, err, want)
+	}
+
+	_, err = ow.Device("26.000000000002").ReadDS2438()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("no humidity: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,175 @@
+package ownet
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Error code returned by owserver. Raw code can be retrieved from errors
+// returned by this package with errors.As; errors.Is matches it against the
+// named errors below.
+type OWErr int32
+
+func (e OWErr) Error() string {
+	if msg, ok := errorMessages[e]; ok {
+		return fmt.Sprintf("ownet: owserver error %d (%s)", int32(e), msg)
+	}
+	return fmt.Sprintf("ownet: owserver error %d", int32(e))
+}
+
+// Get numeric error code, as returned by owserver.
+func (e OWErr) Code() int32 {
+	return int32(e)
+}
+
+// owserver error codes, negated errno values
+const (
+	errNoEntry  OWErr = -2  // ENOENT
+	errAccess   OWErr = -13 // EACCES
+	errNoDevice OWErr = -19 // ENODEV
+	errNotDir   OWErr = -20 // ENOTDIR
+)
+
+// Descriptions of common owserver error codes
+var errorMessages = map[OWErr]string{
+	-1:          "generic error",
+	errNoEntry:  "no such entity",
+	-5:          "input/output error",
+	-11:         "try again",
+	-12:         "out of memory",
+	errAccess:   "permission denied",
+	-14:         "bad address",
+	-16:         "device busy",
+	errNoDevice: "no such device",
+	errNotDir:   "not a directory",
+	-21:         "is a directory",
+	-22:         "invalid argument",
+	-34:         "value out of range",
+	-36:         "name too long",
+	-42:         "no message",
+	-71:         "protocol error",
+	-95:         "operation not supported",
+	-110:        "timed out",
+}
+
+// Errors matching owserver error codes with errors.Is:
+//
+//	ErrNotFound       -2 ENOENT, -19 ENODEV (no such path, or device is gone)
+//	ErrNotADirectory  -20 ENOTDIR
+//	ErrPermission     -13 EACCES
+//
+// ErrNotFound and ErrPermission are also matched by fs.ErrNotExist and
+// fs.ErrPermission.
+var (
+	ErrNotFound      = errors.New("ownet: not found")
+	ErrNotADirectory = errors.New("ownet: not a directory")
+	ErrPermission    = errors.New("ownet: permission denied")
+)
+
+// Errors that are not owserver error codes
+var (
+	// Matches any dial or socket failure with errors.Is
+	ErrConnection = errors.New("ownet: connection failed")
+
+	// Returned for paths that can not be sent to owserver
+	ErrInvalidPath = errors.New("ownet: invalid path")
+
+	// Returned for writes of no data, which owserver has no meaning for
+	ErrEmptyWrite = errors.New("ownet: empty write")
+
+	// Returned for offsets and sizes not fitting into request header
+	ErrOutOfRange = errors.New("ownet: out of range")
+
+	// Returned for features that owserver does not support
+	ErrUnsupported = errors.New("ownet: not supported by owserver")
+
+	// Matches BufferTooSmallError with errors.Is
+	ErrBufferTooSmall = errors.New("ownet: buffer too small")
+)
+
+// Error of reading a response that does not fit into the buffer. The part
+// that fits is read and the rest is discarded, so the connection stays
+// usable, and the read can be retried with a buffer of Size bytes.
+type BufferTooSmallError struct {
+	Size int // size of the response
+}
+
+func (e *BufferTooSmallError) Error() string {
+	return fmt.Sprintf("ownet: response of %d bytes does not fit into buffer", e.Size)
+}
+
+func (e *BufferTooSmallError) Is(target error) bool {
+	return target == ErrBufferTooSmall
+}
+
+// Report whether e is matched by target, one of the named errors or the
+// corresponding io/fs error.
+func (e OWErr) Is(target error) bool {
+	switch target {
+	case ErrNotFound, fs.ErrNotExist:
+		return e == errNoEntry || e == errNoDevice
+	case ErrNotADirectory:
+		return e == errNotDir
+	case ErrPermission, fs.ErrPermission:
+		return e == errAccess
+	}
+	return false
+}
+
+// Error of an operation on owserver path, wrapping the cause, which can be
+// retrieved with errors.As, like OWErr, or matched with errors.Is.
+type OpError struct {
+	Op   string // operation, like "read" or "dir"
+	Path string // owserver path
+	Err  error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("ownet: %s %s: %s", e.Op, e.Path, strings.TrimPrefix(e.Err.Error(), "ownet: "))
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Get error signaled in response header hdr. owserver signals errors the
+// same way in responses to all message types: negative Type is a negated
+// errno code. Non-negative Type is the return value, like the number of bytes
+// read or the size of a file, and is 0 or the payload length for directory
+// listings, depending on owserver version.
+func responseError(hdr Header) error {
+	if hdr.Type < 0 {
+		return OWErr(hdr.Type)
+	}
+	return nil
+}
+
+// Wrap err, if not nil or already wrapped, in OpError.
+func opError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*OpError); ok {
+		return err
+	}
+	return &OpError{Op: op, Path: path, Err: err}
+}
+
+// Dial or socket failure
+type connError struct {
+	err error
+}
+
+func (e *connError) Error() string {
+	return "ownet: connection failed: " + e.err.Error()
+}
+
+func (e *connError) Unwrap() error {
+	return e.err
+}
+
+func (e *connError) Is(target error) bool {
+	return target == ErrConnection
+}
@@ -0,0 +1,202 @@
+package ownet
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestErrorsIs(t *testing.T) {
+	tests := []struct {
+		err    error
+		target error
+		want   bool
+	}{
+		{OWErr(-2), ErrNotFound, true},
+		{OWErr(-19), ErrNotFound, true},
+		{OWErr(-20), ErrNotADirectory, true},
+		{OWErr(-13), ErrPermission, true},
+		{OWErr(-2), ErrPermission, false},
+		{OWErr(-2), ErrConnection, false},
+		{&connError{io.EOF}, ErrConnection, true},
+		{&connError{io.EOF}, io.EOF, true},
+		{&connError{io.EOF}, ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	_, err := ow.GetAttr("28.000000000000", "temperature")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing device: got %v, want ErrNotFound", err)
+	}
+	var code OWErr
+	if !errors.As(err, &code) || code != -2 {
+		t.Errorf("missing device: got code %v, want -2", code)
+	}
+}
+
+func TestOpError(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	_, err := ow.Read("/nonexistent", 0, make([]byte, 8))
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Op != "read" || opErr.Path != "/nonexistent" {
+		t.Fatalf("got %#v, want OpError of read /nonexistent", err)
+	}
+	if want := "ownet: read /nonexistent: owserver error -2 (no such entity)"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+	var code OWErr
+	if !errors.As(err, &code) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("cause %v not retrievable", err)
+	}
+
+	_, err = ow.Dir("/nonexistent")
+	if !errors.As(err, &opErr) || opErr.Op != "dir" {
+		t.Errorf("dir: got %v", err)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  OWErr
+		want string
+	}{
+		{OWErr(-2), "ownet: owserver error -2 (no such entity)"},
+		{OWErr(-22), "ownet: owserver error -22 (invalid argument)"},
+		{OWErr(-1000), "ownet: owserver error -1000"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+		if tt.err.Code() != int32(tt.err) {
+			t.Errorf("Code() = %d, want %d", tt.err.Code(), int32(tt.err))
+		}
+	}
+}
+
+func TestServerErrorCodes(t *testing.T) {
+	s := newTestServer(t)
+	s.fail = map[string]OWErr{
+		"/3A.BEE71B000000/PIO.A": errAccess,
+		"/28.A1B2C3000000":       errNoDevice,
+		"/settings/units":        errNotDir,
+		"/bus.0":                 -5,
+	}
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	if err := ow.SetAttr("3A.BEE71B000000", "PIO.A", "1"); !errors.Is(err, ErrPermission) {
+		t.Errorf("EACCES: got %v", err)
+	}
+	if _, err := ow.Dir("/28.A1B2C3000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ENODEV: got %v", err)
+	}
+	if _, err := ow.Dir("/settings/units"); !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("ENOTDIR: got %v", err)
+	}
+	var code OWErr
+	if _, err := ow.Dir("/bus.0"); !errors.As(err, &code) || code.Code() != -5 {
+		t.Errorf("EIO: got %v", err)
+	}
+	// errors do not break the persistent connection
+	if typ, err := ow.GetType("3A.BEE71B000000"); err != nil || typ != "DS2413" {
+		t.Errorf("after errors: got %q, %v", typ, err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestBufferTooSmall(t *testing.T) {
+	s := newTestServer(t)
+	s.oversize = true
+	ow := New(s.addr(), WithPersistent(), WithDefaultBufferSize(2))
+	defer ow.Close()
+
+	data := make([]byte, 4)
+	n, err := ow.Read("/28.A1B2C3000000/type", 0, data)
+	var tooSmall *BufferTooSmallError
+	if !errors.As(err, &tooSmall) || tooSmall.Size != 7 || !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("got %v, want BufferTooSmallError of 7 bytes", err)
+	}
+	if string(data[:n]) != "DS18" {
+		t.Errorf("got %q, want %q", data[:n], "DS18")
+	}
+	// excess data was discarded, and reading whole values is not affected
+	if typ, err := ow.GetType("28.A1B2C3000000"); err != nil || typ != "DS18B20" {
+		t.Errorf("GetType: got %q, %v", typ, err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	_, err := ow.Read(attr, -1, make([]byte, 1))
+	if want := "ownet: read " + attr + ": out of range: offset -1"; !errors.Is(err, ErrOutOfRange) || err.Error() != want {
+		t.Errorf("negative read offset: got %v, want %q", err, want)
+	}
+	if err := ow.Write(attr, 1<<31, []byte("1")); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("write offset over int32: got %v", err)
+	}
+	if _, _, err := ow.RawRequest(MsgRead, attr, nil, 0, -1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("negative raw size: got %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.requests); n != 0 {
+		t.Errorf("%d requests sent, want 0", n)
+	}
+}
+
+func TestResponseErrorRule(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	// positive Type is a return value, like payload length, for all requests
+	s.mu.Lock()
+	s.dirType = 80
+	s.mu.Unlock()
+	if items, err := ow.Dir("/3A.BEE71B000000"); err != nil || len(items) != 3 {
+		t.Errorf("dir: got %q, %v", items, err)
+	}
+	if typ, err := ow.GetType("3A.BEE71B000000"); err != nil || typ != "DS2413" {
+		t.Errorf("read: got %q, %v", typ, err)
+	}
+
+	// negative Type is an error code for all requests
+	s.mu.Lock()
+	s.fail = map[string]OWErr{"/3A.BEE71B000000": -5, "/3A.BEE71B000000/PIO.A": -5}
+	s.mu.Unlock()
+	var code OWErr
+	if _, err := ow.Dir("/3A.BEE71B000000"); !errors.As(err, &code) || code != -5 {
+		t.Errorf("dir: got %v", err)
+	}
+	if _, err := ow.GetAttr("3A.BEE71B000000", "PIO.A"); !errors.As(err, &code) || code != -5 {
+		t.Errorf("read: got %v", err)
+	}
+	if err := ow.SetAttr("3A.BEE71B000000", "PIO.A", "1"); !errors.As(err, &code) || code != -5 {
+		t.Errorf("write: got %v", err)
+	}
+	if _, err := ow.Size("/3A.BEE71B000000/PIO.A"); !errors.As(err, &code) || code != -5 {
+		t.Errorf("size: got %v", err)
+	}
+	if _, err := ow.Presence("/3A.BEE71B000000/PIO.A"); !errors.As(err, &code) || code != -5 {
+		t.Errorf("presence: got %v", err)
+	}
+}
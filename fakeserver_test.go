@@ -0,0 +1,319 @@
+package ownet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// In-process owserver speaking enough of the protocol for the client tests.
+// Files map full paths of attributes to their values; directories are
+// derived from the paths, and paths ending with slash denote empty ones.
+// Besides listening on a socket, it can serve connections created otherwise,
+// e.g. with net.Pipe in a DialFunc, see serve.
+type fakeServer struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	files     map[string]string
+	persist   bool             // grant persistence when requested
+	delay     time.Duration    // delay before each response
+	drop      int              // number of requests to drop connection on
+	pings     int              // number of keepalive headers sent before each response
+	fail      map[string]OWErr // error codes returned for any request of paths
+	version   int32            // protocol version sent in responses
+	oversize  bool             // ignore size of read requests, sending whole values
+	dirType   int32            // Type of successful responses to listing requests
+	dials     int              // number of accepted connections
+	active    int              // number of open connections
+	maxActive int              // maximum number of simultaneously open connections
+	requests  []Header         // headers of received requests
+	conns     []net.Conn
+}
+
+func newFakeServer(t *testing.T, files map[string]string) *fakeServer {
+	return newFakeServerOn(t, "tcp", "127.0.0.1:0", files)
+}
+
+// Same as newFakeServer, but listening at address on named network.
+func newFakeServerOn(t *testing.T, network, address string, files map[string]string) *fakeServer {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		ln:      ln,
+		files:   files,
+		persist: true,
+	}
+	go s.accept()
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.dials++
+		s.active++
+		if s.active > s.maxActive {
+			s.maxActive = s.active
+		}
+		s.conns = append(s.conns, c)
+		s.mu.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *fakeServer) close() {
+	s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close all open connections, keeping the server listening.
+func (s *fakeServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+// Get number of accepted connections.
+func (s *fakeServer) dialCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dials
+}
+
+// Get maximum number of simultaneously open connections.
+func (s *fakeServer) maxActiveCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxActive
+}
+
+// Drop connection instead of responding to the next n requests.
+func (s *fakeServer) dropNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop = n
+}
+
+// Get value of file at path.
+func (s *fakeServer) file(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[path]
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	for {
+		var hdr Header
+		if err := binary.Read(c, binary.BigEndian, &hdr); err != nil {
+			return
+		}
+		payload := make([]byte, hdr.Payload)
+		if _, err := io.ReadFull(c, payload); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, hdr)
+		drop := s.drop > 0
+		if drop {
+			s.drop--
+		}
+		delay, persist, pings, version := s.delay, s.persist, s.pings, s.version
+		s.mu.Unlock()
+		if drop {
+			return
+		}
+		time.Sleep(delay)
+
+		if hdr.Type == MsgDir {
+			if !s.serveDir(c, hdr, payload) {
+				return
+			}
+			continue
+		}
+		ret, data := s.handle(hdr, payload)
+		resp := Header{
+			Version: version,
+			Payload: int32(len(data)),
+			Type:    ret,
+			Size:    int32(len(data)),
+			Offset:  hdr.Offset,
+		}
+		keep := persist && hdr.Flags&flagPersistence != 0
+		if keep {
+			resp.Flags = flagPersistence
+		}
+		var buf bytes.Buffer
+		for i := 0; i < pings; i++ {
+			binary.Write(&buf, binary.BigEndian, Header{Payload: -1})
+		}
+		binary.Write(&buf, binary.BigEndian, resp)
+		buf.Write(data)
+		if _, err := c.Write(buf.Bytes()); err != nil || !keep {
+			return
+		}
+	}
+}
+
+// Respond to MsgDir request with a message per item, terminated by empty one.
+// Reports whether connection is kept open.
+func (s *fakeServer) serveDir(c net.Conn, hdr Header, payload []byte) bool {
+	path := strings.TrimRight(string(payload), "\x00")
+	s.mu.Lock()
+	items, ok := s.list(path, false)
+	keep := s.persist && hdr.Flags&flagPersistence != 0
+	s.mu.Unlock()
+	var buf bytes.Buffer
+	send := func(ret int32, data []byte) {
+		resp := Header{Payload: int32(len(data)), Type: ret, Size: int32(len(data))}
+		if keep {
+			resp.Flags = flagPersistence
+		}
+		binary.Write(&buf, binary.BigEndian, resp)
+		buf.Write(data)
+	}
+	if !ok {
+		send(int32(errNoEntry), nil)
+	} else {
+		for _, item := range items {
+			send(0, append([]byte(item), 0))
+		}
+		send(0, nil)
+	}
+	_, err := c.Write(buf.Bytes())
+	return err == nil && keep
+}
+
+// Handle request, returning value of Type field of the response and payload.
+func (s *fakeServer) handle(hdr Header, payload []byte) (int32, []byte) {
+	path := string(payload)
+	if i := strings.IndexByte(path, 0); i >= 0 {
+		path, payload = path[:i], payload[i+1:]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if code, ok := s.fail[path]; ok {
+		return int32(code), nil
+	}
+	switch hdr.Type {
+	case MsgNop:
+		return 0, nil
+	case MsgRead:
+		v, ok := s.files[path]
+		if !ok {
+			return int32(errNoEntry), nil
+		}
+		if int(hdr.Offset) >= len(v) {
+			return 0, nil
+		}
+		v = v[hdr.Offset:]
+		if len(v) > int(hdr.Size) && !s.oversize {
+			v = v[:hdr.Size]
+		}
+		return int32(len(v)), []byte(v)
+	case MsgWrite:
+		if _, ok := s.files[path]; !ok {
+			return int32(errNoEntry), nil
+		}
+		s.files[path] = string(payload[:hdr.Size])
+		return 0, nil
+	case MsgSize:
+		v, ok := s.files[path]
+		if !ok {
+			return int32(errNoEntry), nil
+		}
+		return int32(len(v)), nil
+	case MsgPresence:
+		if !s.exists(path) {
+			return int32(errNoEntry), nil
+		}
+		return 0, nil
+	case MsgGet, MsgGetSlash:
+		if v, ok := s.files[path]; ok {
+			return int32(len(v)), []byte(v)
+		}
+		items, ok := s.list(path, hdr.Type == MsgGetSlash)
+		if !ok {
+			return int32(errNoEntry), nil
+		}
+		return 0, []byte(strings.Join(items, ","))
+	case MsgDirAll, MsgDirAllSlash:
+		items, ok := s.list(path, hdr.Type == MsgDirAllSlash)
+		if !ok {
+			return int32(errNoEntry), nil
+		}
+		return s.dirType, []byte(strings.Join(items, ","))
+	}
+	return -1, nil
+}
+
+// Report whether path is a file or a directory. Caller must hold the lock.
+func (s *fakeServer) exists(path string) bool {
+	if _, ok := s.files[path]; ok {
+		return true
+	}
+	_, ok := s.list(path, false)
+	return ok
+}
+
+// List directory at path, optionally appending slash to subdirectories.
+// Caller must hold the lock.
+func (s *fakeServer) list(path string, slash bool) (items []string, ok bool) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	seen := make(map[string]bool)
+	for name := range s.files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ok = true
+		item := name[len(prefix):]
+		if item == "" {
+			continue
+		}
+		dir := false
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			item, dir = item[:i], true
+		}
+		item = prefix + item
+		if dir && slash {
+			item += "/"
+		}
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	sort.Strings(items)
+	return
+}
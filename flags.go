@@ -0,0 +1,228 @@
+package ownet
+
+import (
+	"regexp"
+)
+
+// Flag bits of the control flags word sent with every request.
+//
+//	bit  1      list bus.N directories, which owserver includes in listings
+//	bit  2      persistent connection
+//	bit  3      use device aliases in listings
+//	bit  4      safe mode, owserver refuses writes
+//	bit  5      uncached read
+//	bit  8      request comes from an ownet client library
+//	bits 16-17  temperature scale
+//	bits 18-20  pressure scale
+//	bits 24-26  device display format
+//
+// Flags word is read on every request, so it is guarded by the OW mutex.
+const (
+	flagBusRet      int32 = 0x02  // list bus.N directories
+	flagPersistence int32 = 0x04  // ask owserver to keep connection open
+	flagAlias       int32 = 0x08  // list aliases instead of device identifiers
+	flagSafeMode    int32 = 0x10  // owserver in safe mode
+	flagUncached    int32 = 0x20  // bypass owserver value cache
+	flagOwnet       int32 = 0x100 // ownet client request
+
+	flagTempScaleShift = 16
+	flagTempScaleMask  = 0x3 << flagTempScaleShift
+
+	flagPressureScaleShift = 18
+	flagPressureScaleMask  = 0x7 << flagPressureScaleShift
+
+	flagFormatShift = 24
+	flagFormatMask  = 0x7 << flagFormatShift
+
+	// Flags of new clients: Celsius, millibar, f.i format, cached reads
+	defaultFlags = flagOwnet | flagBusRet
+)
+
+// Get the control flags word sent with every request, made of the bits set
+// by SetUncached, SetTemperatureScale, SetPressureScale and SetDisplayFormat.
+// Persistence bit is not included, it is added to each request in persistent
+// mode.
+func (ow *OW) Flags() uint32 {
+	ow.Lock()
+	defer ow.Unlock()
+	return uint32(ow.sg)
+}
+
+// Set the whole control flags word sent with every request, for flag
+// combinations not covered by the other setters. Bits are:
+//
+//	0x00000002  list bus.N directories
+//	0x00000004  persistent connection, see SetPersistent
+//	0x00000008  list aliases instead of device identifiers
+//	0x00000010  safe mode
+//	0x00000020  uncached read, see SetUncached
+//	0x00000100  ownet client request
+//	0x00030000  temperature scale, see SetTemperatureScale
+//	0x001C0000  pressure scale, see SetPressureScale
+//	0x07000000  device display format, see SetDisplayFormat
+//
+// Default is 0x102. Setting persistence bit makes owserver keep connections
+// open even when not in persistent mode, which the client then closes.
+func (ow *OW) SetFlags(flags uint32) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.sg = int32(flags)
+}
+
+// Replace bits of flags word selected by mask with v shifted into place.
+// Caller must hold the lock.
+func (ow *OW) setFlagBits(mask int32, shift uint, v int32) {
+	ow.sg = ow.sg&^mask | v<<shift&mask
+}
+
+// Get bits of flags word selected by mask. Caller must hold the lock.
+func (ow *OW) flagBits(mask int32, shift uint) int32 {
+	return ow.sg & mask >> shift
+}
+
+// Enable or disable uncached mode. In uncached mode every read bypasses
+// owserver value cache and fetches fresh data from the device.
+func (ow *OW) SetUncached(on bool) {
+	ow.Lock()
+	defer ow.Unlock()
+	if on {
+		ow.sg |= flagUncached
+	} else {
+		ow.sg &^= flagUncached
+	}
+}
+
+// Temperature scale used by owserver for temperature values
+type TemperatureScale int32
+
+const (
+	Celsius TemperatureScale = iota
+	Fahrenheit
+	Kelvin
+	Rankine
+)
+
+func (s TemperatureScale) String() string {
+	switch s {
+	case Celsius:
+		return "C"
+	case Fahrenheit:
+		return "F"
+	case Kelvin:
+		return "K"
+	case Rankine:
+		return "R"
+	}
+	return "unknown"
+}
+
+// Set temperature scale for values returned by owserver.
+func (ow *OW) SetTemperatureScale(s TemperatureScale) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.setFlagBits(flagTempScaleMask, flagTempScaleShift, int32(s))
+}
+
+// Get temperature scale currently used for values returned by owserver.
+func (ow *OW) TemperatureScale() TemperatureScale {
+	ow.Lock()
+	defer ow.Unlock()
+	return TemperatureScale(ow.flagBits(flagTempScaleMask, flagTempScaleShift))
+}
+
+// Pressure scale used by owserver for pressure values
+type PressureScale int32
+
+const (
+	Millibar PressureScale = iota
+	Atmosphere
+	MillimeterHg
+	InchHg
+	PSI
+	Pascal
+)
+
+func (s PressureScale) String() string {
+	switch s {
+	case Millibar:
+		return "mbar"
+	case Atmosphere:
+		return "atm"
+	case MillimeterHg:
+		return "mmHg"
+	case InchHg:
+		return "inHg"
+	case PSI:
+		return "psi"
+	case Pascal:
+		return "Pa"
+	}
+	return "unknown"
+}
+
+// Set pressure scale for values returned by owserver.
+func (ow *OW) SetPressureScale(s PressureScale) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.setFlagBits(flagPressureScaleMask, flagPressureScaleShift, int32(s))
+}
+
+// Get pressure scale currently used for values returned by owserver.
+func (ow *OW) PressureScale() PressureScale {
+	ow.Lock()
+	defer ow.Unlock()
+	return PressureScale(ow.flagBits(flagPressureScaleMask, flagPressureScaleShift))
+}
+
+// Format of device identifiers in owserver directory listings and paths:
+// family code (f), serial number (i) and CRC (c), optionally dot-separated.
+type DeviceFormat int32
+
+const (
+	FormatFDotI     DeviceFormat = iota // f.i, e.g. 3A.BEE71B000000
+	FormatFI                            // fi, e.g. 3ABEE71B000000
+	FormatFDotIDotC                     // f.i.c, e.g. 3A.BEE71B000000.C2
+	FormatFDotIC                        // f.ic, e.g. 3A.BEE71B000000C2
+	FormatFIDotC                        // fi.c, e.g. 3ABEE71B000000.C2
+	FormatFIC                           // fic, e.g. 3ABEE71B000000C2
+)
+
+var formatNames = [...]string{"f.i", "fi", "f.i.c", "f.ic", "fi.c", "fic"}
+
+var formatRegex = [...]*regexp.Regexp{
+	nil, // DeviceRegex
+	regexp.MustCompile("(?i)[0-9A-F]{14}"),
+	regexp.MustCompile("(?i)[0-9A-F]{2}\\.[0-9A-F]{12}\\.[0-9A-F]{2}"),
+	regexp.MustCompile("(?i)[0-9A-F]{2}\\.[0-9A-F]{14}"),
+	regexp.MustCompile("(?i)[0-9A-F]{14}\\.[0-9A-F]{2}"),
+	regexp.MustCompile("(?i)[0-9A-F]{16}"),
+}
+
+func (f DeviceFormat) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return "unknown"
+	}
+	return formatNames[f]
+}
+
+// Regexp matching device identifiers in format f.
+func (f DeviceFormat) regex() *regexp.Regexp {
+	if f <= 0 || int(f) >= len(formatRegex) {
+		return DeviceRegex
+	}
+	return formatRegex[f]
+}
+
+// Set format of device identifiers returned by owserver.
+func (ow *OW) SetDisplayFormat(f DeviceFormat) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.setFlagBits(flagFormatMask, flagFormatShift, int32(f))
+}
+
+// Get format of device identifiers currently returned by owserver.
+func (ow *OW) DisplayFormat() DeviceFormat {
+	ow.Lock()
+	defer ow.Unlock()
+	return DeviceFormat(ow.flagBits(flagFormatMask, flagFormatShift))
+}
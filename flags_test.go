@@ -0,0 +1,64 @@
+package ownet
+
+import (
+	"testing"
+)
+
+func TestScales(t *testing.T) {
+	ow := New("")
+
+	ow.SetTemperatureScale(Kelvin)
+	ow.SetPressureScale(Pascal)
+	if s := ow.TemperatureScale(); s != Kelvin {
+		t.Errorf("temperature scale: got %v, want %v", s, Kelvin)
+	}
+	if s := ow.PressureScale(); s != Pascal {
+		t.Errorf("pressure scale: got %v, want %v", s, Pascal)
+	}
+	if ow.sg != 0x160102 {
+		t.Errorf("flags: got %#x, want %#x", ow.sg, 0x160102)
+	}
+}
+
+func TestDisplayFormat(t *testing.T) {
+	ids := map[DeviceFormat]string{
+		FormatFDotI:     "3A.BEE71B000000",
+		FormatFI:        "3ABEE71B000000",
+		FormatFDotIDotC: "3A.BEE71B000000.C2",
+		FormatFDotIC:    "3A.BEE71B000000C2",
+		FormatFIDotC:    "3ABEE71B000000.C2",
+		FormatFIC:       "3ABEE71B000000C2",
+	}
+	ow := New("")
+	for f, id := range ids {
+		ow.SetDisplayFormat(f)
+		if got := ow.DisplayFormat(); got != f {
+			t.Errorf("format: got %v, want %v", got, f)
+		}
+		if got := f.regex().FindString("/" + id); got != id {
+			t.Errorf("%v: matched %q, want %q", f, got, id)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	if f := ow.Flags(); f != 0x102 {
+		t.Errorf("default: got %#x, want %#x", f, 0x102)
+	}
+	ow.SetFlags(0x100)
+	ow.SetTemperatureScale(Fahrenheit)
+	if f := ow.Flags(); f != 0x10100 {
+		t.Errorf("got %#x, want %#x", f, 0x10100)
+	}
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f := s.requests[0].Flags; f != 0x10100 {
+		t.Errorf("sent %#x, want %#x", f, 0x10100)
+	}
+}
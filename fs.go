@@ -0,0 +1,213 @@
+package ownet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File system view of owserver namespace: directories are owserver
+// directories, files are attributes. Implements fs.FS, fs.ReadDirFS,
+// fs.ReadFileFS and fs.StatFS, so that it can be used with io/fs functions
+// such as fs.WalkDir and fs.ReadFile. Files are read as a whole when opened.
+type FS struct {
+	ow *OW
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+)
+
+// Get file system view of owserver namespace.
+func (ow *OW) FS() *FS {
+	return &FS{ow: ow}
+}
+
+// Convert fs.FS name to owserver path.
+func fsPath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "/", nil
+	}
+	return "/" + name, nil
+}
+
+// Open named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	p, err := fsPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	items, err := f.ow.dir(context.Background(), p, MsgDirAllSlash)
+	if err == nil {
+		return &dirFile{info: dirInfo(name), entries: f.entries(p, items)}, nil
+	}
+	if !errors.Is(err, ErrNotADirectory) && !errors.Is(err, ErrNotFound) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	data, err := f.ow.ReadAll(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	info := &fileInfo{name: path.Base(name), size: int64(len(data))}
+	return &file{info: info, Reader: bytes.NewReader(data)}, nil
+}
+
+// Read named directory, returning entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := fsPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	items, err := f.ow.dir(context.Background(), p, MsgDirAllSlash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return f.entries(p, items), nil
+}
+
+// Read named file as a whole.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	p, err := fsPath("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.ow.ReadAll(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// Get information about named file or directory. Size of a file is the
+// size reported by owserver, see OW.Size.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	p, err := fsPath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.ow.dir(context.Background(), p, MsgDirAllSlash)
+	if err == nil {
+		return dirInfo(name), nil
+	}
+	if !errors.Is(err, ErrNotADirectory) && !errors.Is(err, ErrNotFound) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	size, err := f.ow.Size(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return &fileInfo{name: path.Base(name), size: int64(size)}, nil
+}
+
+// Convert listing of directory at owserver path p to sorted entries.
+func (f *FS) entries(p string, items []string) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(items))
+	for _, item := range items {
+		name := path.Base(strings.TrimSuffix(item, "/"))
+		entries = append(entries, &dirEntry{
+			fsys: f,
+			name: strings.TrimPrefix(path.Join(p, name), "/"),
+			dir:  strings.HasSuffix(item, "/"),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func dirInfo(name string) *fileInfo {
+	return &fileInfo{name: path.Base(name), dir: true}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type dirEntry struct {
+	fsys *FS
+	name string // full fs.FS name
+	dir  bool
+}
+
+func (de *dirEntry) Name() string { return path.Base(de.name) }
+func (de *dirEntry) IsDir() bool  { return de.dir }
+
+func (de *dirEntry) Type() fs.FileMode {
+	if de.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (de *dirEntry) Info() (fs.FileInfo, error) {
+	if de.dir {
+		return dirInfo(de.name), nil
+	}
+	return de.fsys.Stat(de.name)
+}
+
+// Attribute file, with contents read when opened
+type file struct {
+	info *fileInfo
+	*bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+// Directory file, with entries listed when opened
+type dirFile struct {
+	info    *fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
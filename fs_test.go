@@ -0,0 +1,56 @@
+package ownet
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	fsys := New(newTestServer(t).addr()).FS()
+
+	if err := fstest.TestFS(fsys, "3A.BEE71B000000/PIO.A", "settings/units/temperature_scale"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "28.A1B2C3000000/type")
+	if err != nil || string(data) != "DS18B20" {
+		t.Errorf("ReadFile: got %q, %v", data, err)
+	}
+
+	var files []string
+	err = fs.WalkDir(fsys, "3A.BEE71B000000", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("WalkDir: got %q", files)
+	}
+
+	if _, err := fsys.Stat("nonexistent"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat: got %v, want not exist", err)
+	}
+}
+
+func TestFSPath(t *testing.T) {
+	for name, want := range map[string]string{
+		".":                    "/",
+		"28.A1B2C3000000":      "/28.A1B2C3000000",
+		".hidden":              "/.hidden",
+		"settings/units/.x":    "/settings/units/.x",
+		"28.A1B2C3000000/type": "/28.A1B2C3000000/type",
+	} {
+		if got, err := fsPath("open", name); err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
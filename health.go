@@ -0,0 +1,47 @@
+package ownet
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// Time limit of each check done by HealthCheck
+const healthCheckTimeout = 5 * time.Second
+
+// Result of HealthCheck
+type Health struct {
+	ServerReachable bool  // owserver responds to MsgNop
+	BusResponsive   bool  // listing of the bus succeeds
+	DeviceCount     int   // number of devices listed on the bus
+	Err             error // failure of the first check that did not pass
+}
+
+// Check health of owserver and of the 1-Wire bus separately, so that a
+// misbehaving bus can be told from owserver being down. Each check is
+// limited in time on its own, and the bus is not checked if owserver is not
+// reachable.
+func (ow *OW) HealthCheck() (h Health) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	if h.Err = ow.PingContext(ctx); h.Err != nil {
+		return
+	}
+	h.ServerReachable = true
+
+	ctx, cancel = context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	items, err := ow.dir(ctx, "/", MsgDirAll)
+	if err != nil {
+		h.Err = err
+		return
+	}
+	h.BusResponsive = true
+	re := ow.DisplayFormat().regex()
+	for _, item := range items {
+		if re.MatchString(path.Base(item)) {
+			h.DeviceCount++
+		}
+	}
+	return
+}
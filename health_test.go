@@ -0,0 +1,21 @@
+package ownet
+
+import (
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	h := ow.HealthCheck()
+	if !h.ServerReachable || !h.BusResponsive || h.DeviceCount != 2 || h.Err != nil {
+		t.Errorf("healthy: got %+v", h)
+	}
+
+	s.close()
+	h = ow.HealthCheck()
+	if h.ServerReachable || h.BusResponsive || h.Err == nil {
+		t.Errorf("down: got %+v", h)
+	}
+}
@@ -0,0 +1,31 @@
+package ownet
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Prefix of owserver JSON view of the namespace
+const jsonPrefix = "/json"
+
+// Read JSON representation of owserver directory at path, with its
+// attributes and subdirectories, in a single request. JSON view is provided
+// by owserver 3.2 and newer.
+// Returns decoded JSON and error if any, ErrUnsupported if owserver has no
+// JSON view.
+func (ow *OW) DirJSON(path string) (map[string]any, error) {
+	data, err := ow.ReadString(jsonPrefix + "/" + path)
+	if errors.Is(err, ErrNotFound) {
+		if ok, perr := ow.Exists(jsonPrefix); perr == nil && !ok {
+			return nil, opError("read", jsonPrefix, ErrUnsupported)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var v map[string]any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil, opError("read", jsonPrefix+"/"+path, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,36 @@
+package ownet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDirJSON(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/json/28.A1B2C3000000": `{"type":"DS18B20","temperature":21.5,"errata":{"die":"C2"}}`,
+		"/json/bad":             `{"type"`,
+	})
+	ow := New(s.addr())
+
+	v, err := ow.DirJSON("28.A1B2C3000000")
+	want := map[string]any{
+		"type":        "DS18B20",
+		"temperature": 21.5,
+		"errata":      map[string]any{"die": "C2"},
+	}
+	if err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("got %v, %v", v, err)
+	}
+	if _, err := ow.DirJSON("bad"); err == nil {
+		t.Error("invalid JSON: no error")
+	}
+	if _, err := ow.DirJSON("28.000000000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing device: got %v, want ErrNotFound", err)
+	}
+
+	old := New(newTestServer(t).addr())
+	if _, err := old.DirJSON("28.A1B2C3000000"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("no JSON view: got %v, want ErrUnsupported", err)
+	}
+}
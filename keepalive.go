@@ -0,0 +1,85 @@
+package ownet
+
+import (
+	"context"
+	"time"
+)
+
+// Check that owserver is responsive by sending MsgNop request, which does not
+// touch any device. In persistent mode it is sent over the open connection,
+// if any, thus also verifying that the connection is still alive.
+// Returns error if any.
+func (ow *OW) Ping() error {
+	return ow.PingContext(context.Background())
+}
+
+// Same as Ping, but request is aborted when ctx is done.
+func (ow *OW) PingContext(ctx context.Context) (err error) {
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer ow.release(c)
+	return c.nop(ctx)
+}
+
+// Start pinging owserver every interval in background, to keep persistent
+// connection from being dropped as idle by firewalls. In pool mode every idle
+// connection is pinged. Pings are serialized with other requests the same
+// way requests are serialized with each other, so they never interleave on
+// the wire. Failed pings are logged with logger set by WithLogger, if any;
+// the connection is re-dialed by the next request. Non-positive interval is
+// taken as one second.
+// Returns function stopping the keepalive, which waits for a ping in flight
+// to finish.
+func (ow *OW) StartKeepalive(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := ow.keepalive(ctx); err != nil && ctx.Err() == nil && ow.logger != nil {
+					ow.logger.Printf("keepalive: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Ping owserver for StartKeepalive. In pool mode all idle connections are
+// taken at once, so that each of them is pinged, and the first error is
+// returned.
+func (ow *OW) keepalive(ctx context.Context) (err error) {
+	if ow.pool == nil {
+		return ow.PingContext(ctx)
+	}
+	ow.Lock()
+	settings := ow.settings
+	ow.Unlock()
+	var conns []*conn
+	for c := ow.pool.getIdle(); c != nil; c = ow.pool.getIdle() {
+		c.ow = ow
+		c.settings = settings
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		if e := c.nop(ctx); e != nil && err == nil {
+			err = e
+		}
+		ow.release(c)
+	}
+	return
+}
@@ -0,0 +1,191 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	s := newFakeServer(t, map[string]string{})
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	if err := ow.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	typ := s.requests[0].Type
+	s.mu.Unlock()
+	if typ != MsgNop {
+		t.Errorf("request type %v, want MsgNop", typ)
+	}
+
+	s.close()
+	if err := ow.Ping(); err == nil {
+		t.Error("ping of closed server succeeded")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	if err := ow.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("Connect dialed %d connections, want 1", n)
+	}
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("connection not reused, dialed %d", n)
+	}
+
+	s.close()
+	ow.Close()
+	if err := ow.Connect(); !errors.Is(err, ErrConnection) {
+		t.Errorf("closed server: got %v, want ErrConnection", err)
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/x/a": "1"})
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	stop := ow.StartKeepalive(5 * time.Millisecond)
+	for i := 0; i < 20; i++ {
+		if v, err := ow.GetAttr("x", "a"); err != nil || v != "1" {
+			t.Fatalf("got %q, %v", v, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nops := 0
+	for _, hdr := range s.requests {
+		if hdr.Type == MsgNop {
+			nops++
+		}
+	}
+	if nops == 0 {
+		t.Error("no pings sent")
+	}
+	if s.dials != 1 {
+		t.Errorf("%d dials, want 1", s.dials)
+	}
+}
+
+// Run with -race to check keepalive does not race with other operations on
+// the connection.
+func TestKeepaliveConcurrent(t *testing.T) {
+	s := newTestServer(t)
+	for name, ow := range map[string]*OW{
+		"shared": New(s.addr(), WithPersistent()),
+		"pool":   NewPool(s.addr(), 2),
+	} {
+		stop := ow.StartKeepalive(time.Millisecond)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					buf := make([]byte, 8)
+					if _, err := ow.Read("/28.A1B2C3000000/type", 0, buf); err != nil {
+						t.Errorf("%s: %v", name, err)
+						return
+					}
+					if j == 10 {
+						ow.Close()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		stop()
+		ow.Close()
+	}
+}
+
+func TestServerKeepalive(t *testing.T) {
+	s := newTestServer(t)
+	s.pings = 2
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	for i := 0; i < 3; i++ {
+		if typ, err := ow.GetType("28.A1B2C3000000"); err != nil || typ != "DS18B20" {
+			t.Fatalf("GetType: got %q, %v", typ, err)
+		}
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestKeepalivePool(t *testing.T) {
+	s := newTestServer(t)
+	ow := NewPool(s.addr(), 2)
+	defer ow.Close()
+
+	// two idle connections
+	var conns []*conn
+	for i := 0; i < 2; i++ {
+		c, err := ow.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.nop(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		ow.release(c)
+	}
+
+	if err := ow.keepalive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	nops := 0
+	for _, hdr := range s.requests {
+		if hdr.Type == MsgNop {
+			nops++
+		}
+	}
+	s.mu.Unlock()
+	if nops != 4 {
+		t.Errorf("%d pings, want 4", nops)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+}
+
+func TestNonPositiveInterval(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	ow.StartKeepalive(0)()
+	_, stop := ow.WatchAll([]string{"/28.A1B2C3000000/type"}, -time.Second)
+	stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := NewPoller(ow, []string{"/28.A1B2C3000000/type"}, 0).Start(ctx)
+	if poll := <-polls; len(poll.Results) != 1 {
+		t.Errorf("got %d results, want 1", len(poll.Results))
+	}
+	cancel()
+	for range polls {
+	}
+}
@@ -0,0 +1,149 @@
+package ownet
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Option changes default settings of OW created with New.
+type Option func(*OW)
+
+// Set timeout for establishing connection to owserver. Default is 30 seconds.
+func WithDialTimeout(d time.Duration) Option {
+	return func(ow *OW) {
+		ow.dialTimeout = d
+	}
+}
+
+// Set time limit of each request, same as SetTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(ow *OW) {
+		ow.timeout = d
+	}
+}
+
+// Policy of retrying requests after connection failures
+type RetryPolicy struct {
+	MaxAttempts int           // number of attempts, including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled for each next one
+	MaxDelay    time.Duration // cap of the delay, 0 for none
+}
+
+// Retry requests after connection failures up to maxAttempts times in total,
+// waiting with exponential backoff starting from baseDelay, with random
+// jitter. Requests that owserver responded to with an error are never
+// retried, nor are writes that failed after being sent, other than on a
+// reused connection owserver closed while idle. Waiting is aborted
+// when context of the operation is done. By default a request is retried
+// once without waiting.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return WithRetryPolicy(RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: baseDelay})
+}
+
+// Retry requests after connection failures according to p, see WithRetry.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(ow *OW) {
+		ow.retries = p.MaxAttempts - 1
+		if ow.retries < 0 {
+			ow.retries = 0
+		}
+		ow.retryDelay = p.BaseDelay
+		ow.retryMaxDelay = p.MaxDelay
+	}
+}
+
+// Function establishing connection to owserver at addr on named network
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Establish connections to owserver with f instead of dialing TCP, e.g. to
+// reach it via a proxy. Address is passed to f as given to New, except for
+// default port appended if missing. Dial timeout is not applied, f should
+// respect ctx.
+func WithDialFunc(f DialFunc) Option {
+	return func(ow *OW) {
+		ow.dialFunc = f
+	}
+}
+
+// Perform handshake f on each new connection before sending the first
+// request, e.g. to authenticate to a proxy in front of owserver by sending
+// a token. While f runs, the connection has deadline of the operation's
+// context, or of the request timeout or dial timeout if earlier. Connection
+// is closed if f fails, which is handled like a dial failure. Default is no
+// handshake.
+func WithHandshake(f func(c net.Conn) error) Option {
+	return func(ow *OW) {
+		ow.handshake = f
+	}
+}
+
+// Limit number of connections being established at the same time to n,
+// including handshakes, so that bursts of operations, e.g. of many pollers
+// starting together, do not overwhelm owserver. Other dials wait for their
+// turn, as long as context of their operation is not done. Default is no
+// limit.
+func WithMaxDials(n int) Option {
+	return func(ow *OW) {
+		if n < 1 {
+			n = 1
+		}
+		ow.dialSem = make(chan struct{}, n)
+	}
+}
+
+// Establish connections to owserver with d. Dial timeout set with
+// WithDialTimeout is not applied, d.Timeout is used instead.
+func WithDialer(d *net.Dialer) Option {
+	return WithDialFunc(d.DialContext)
+}
+
+// Set temperature scale, same as SetTemperatureScale. Default is Celsius.
+func WithTemperatureScale(s TemperatureScale) Option {
+	return func(ow *OW) {
+		ow.setFlagBits(flagTempScaleMask, flagTempScaleShift, int32(s))
+	}
+}
+
+// Enable persistent connection mode, same as SetPersistent(true).
+func WithPersistent() Option {
+	return func(ow *OW) {
+		ow.persistent = true
+	}
+}
+
+// Set initial size of buffer used to read values of unknown size, e.g. by
+// GetAttr. Buffer grows as needed, so this only affects number of requests
+// needed to read longer values, while smaller one limits memory used for
+// shorter ones. Default is DefaultReadSize.
+func WithDefaultBufferSize(n int) Option {
+	return func(ow *OW) {
+		if n > 0 {
+			ow.readSize = n
+		}
+	}
+}
+
+// Coalesce concurrent reads of values of the same path, e.g. by GetAttr,
+// ReadString or ReadFloat, into a single request to owserver, whose result
+// or error is shared by all of them. A read started after the one in flight
+// completed sends a new request. Shared read is done with context of the
+// caller that started it.
+func WithSingleFlight() Option {
+	return func(ow *OW) {
+		ow.flights = &flightGroup{}
+	}
+}
+
+// Logger receives debug messages describing headers and payloads of all
+// messages exchanged with owserver. *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Log wire traffic to l. By default nothing is logged.
+func WithLogger(l Logger) Option {
+	return func(ow *OW) {
+		ow.logger = l
+	}
+}
@@ -0,0 +1,256 @@
+package ownet
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	ow := New("")
+	if ow.address != "127.0.0.1:4304" || ow.dialTimeout != 30*time.Second || ow.sg != 0x102 || ow.persistent || ow.readSize != DefaultReadSize {
+		t.Errorf("defaults changed: %+v", ow)
+	}
+
+	ow = New("", WithDialTimeout(time.Second), WithTemperatureScale(Fahrenheit), WithPersistent(), WithDefaultBufferSize(64))
+	if ow.dialTimeout != time.Second {
+		t.Errorf("dial timeout: got %v", ow.dialTimeout)
+	}
+	if s := ow.TemperatureScale(); s != Fahrenheit {
+		t.Errorf("temperature scale: got %v", s)
+	}
+	if !ow.persistent {
+		t.Error("not persistent")
+	}
+	if ow.readSize != 64 {
+		t.Errorf("buffer size: got %v", ow.readSize)
+	}
+	ow.SetDefaultReadSize(32)
+	if ow.readSize != 32 {
+		t.Errorf("buffer size set: got %v", ow.readSize)
+	}
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	s := newTestServer(t)
+	l := &testLogger{}
+	ow := New(s.addr(), WithLogger(l))
+
+	if _, err := ow.GetType("3A.BEE71B000000"); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.lines) != 4 {
+		t.Fatalf("logged %d lines, want 4: %q", len(l.lines), l.lines)
+	}
+	if !strings.Contains(l.lines[3], `"DS2413"`) {
+		t.Errorf("response payload not logged: %q", l.lines[3])
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	s := newTestServer(t)
+	s.version = ProtocolVersion + 1
+	l := &testLogger{}
+	ow := New(s.addr(), WithLogger(l))
+
+	for i := 0; i < 2; i++ {
+		if _, err := ow.GetType("3A.BEE71B000000"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := ow.ServerProtocolVersion(); v != ProtocolVersion+1 {
+		t.Errorf("version: got %d", v)
+	}
+	warnings := 0
+	for _, line := range l.lines {
+		if strings.Contains(line, "newer than supported") {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("logged %d warnings, want 1: %q", warnings, l.lines)
+	}
+}
+
+func TestDialFunc(t *testing.T) {
+	s := newTestServer(t)
+	dialed := 0
+	ow := New("owserver", WithDialFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr != "owserver:4304" {
+			t.Errorf("dialed %q", addr)
+		}
+		dialed++
+		client, server := net.Pipe()
+		go s.serve(server)
+		return client, nil
+	}))
+
+	if typ, err := ow.GetType("3A.BEE71B000000"); err != nil || typ != "DS2413" {
+		t.Fatalf("GetType: got %q, %v", typ, err)
+	}
+	if dialed != 1 {
+		t.Errorf("dialed %d times, want 1", dialed)
+	}
+}
+
+func TestWithHandshake(t *testing.T) {
+	s := newTestServer(t)
+	// proxy expecting token line, acknowledged before owserver protocol
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			line, err := bufio.NewReader(server).ReadString('\n')
+			if err != nil || line != "TOKEN secret\n" {
+				server.Close()
+				return
+			}
+			server.Write([]byte("OK\n"))
+			s.serve(server)
+		}()
+		return client, nil
+	}
+	handshake := func(token string) func(net.Conn) error {
+		return func(c net.Conn) error {
+			if _, err := fmt.Fprintf(c, "TOKEN %s\n", token); err != nil {
+				return err
+			}
+			ack, err := bufio.NewReader(c).ReadString('\n')
+			if err != nil || ack != "OK\n" {
+				return fmt.Errorf("rejected: %q, %v", ack, err)
+			}
+			return nil
+		}
+	}
+
+	ow := New("owserver", WithDialFunc(dial), WithHandshake(handshake("secret")))
+	if typ, err := ow.GetType("3A.BEE71B000000"); err != nil || typ != "DS2413" {
+		t.Errorf("GetType: got %q, %v", typ, err)
+	}
+	ow = New("owserver", WithDialFunc(dial), WithHandshake(handshake("wrong")))
+	if _, err := ow.GetType("3A.BEE71B000000"); !errors.Is(err, ErrConnection) {
+		t.Errorf("rejected handshake: got %v, want ErrConnection", err)
+	}
+
+	// proxy never acknowledging, handshake is limited by request timeout
+	silent := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go io.Copy(io.Discard, server)
+		t.Cleanup(func() { server.Close() })
+		return client, nil
+	}
+	ow = New("owserver", WithDialFunc(silent), WithHandshake(handshake("secret")), WithTimeout(50*time.Millisecond))
+	start := time.Now()
+	if _, err := ow.GetType("3A.BEE71B000000"); !errors.Is(err, ErrConnection) {
+		t.Errorf("unacknowledged handshake: got %v, want ErrConnection", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("unacknowledged handshake took %v", d)
+	}
+}
+
+func TestWithMaxDials(t *testing.T) {
+	s := newTestServer(t)
+	var mu sync.Mutex
+	dialing, maxDialing := 0, 0
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialing++
+		maxDialing = max(maxDialing, dialing)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		dialing--
+		mu.Unlock()
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", s.addr())
+	}
+	ow := New("owserver", WithDialFunc(dial), WithMaxDials(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxDialing != 2 {
+		t.Errorf("%d dials at once, want 2", maxDialing)
+	}
+
+	ow.dialSem <- struct{}{}
+	ow.dialSem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ow.ReadContext(ctx, "/28.A1B2C3000000/type", 0, make([]byte, 8)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting for dial: got %v, want deadline exceeded", err)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	s := newTestServer(t)
+	failures := 3
+	dialed := 0
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed++
+		if dialed <= failures {
+			return nil, errors.New("connection refused")
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", s.addr())
+	}
+	const base = 10 * time.Millisecond
+	ow := New("owserver", WithDialFunc(dial), WithRetry(4, base))
+
+	start := time.Now()
+	if typ, err := ow.GetType("3A.BEE71B000000"); err != nil || typ != "DS2413" {
+		t.Fatalf("GetType: got %q, %v", typ, err)
+	}
+	// at least half of 10, 20 and 40ms
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Errorf("retried after %v, want backoff", d)
+	}
+	if dialed != 4 {
+		t.Errorf("dialed %d times, want 4", dialed)
+	}
+
+	// owserver errors are not retried
+	dialed, failures = 0, 0
+	if _, err := ow.GetType("28.000000000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if dialed != 1 {
+		t.Errorf("owserver error: dialed %d times, want 1", dialed)
+	}
+
+	// waiting is aborted when ctx is done
+	dialed, failures = 0, 10
+	ow = New("owserver", WithDialFunc(dial), WithRetry(10, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := ow.ReadContext(ctx, "/3A.BEE71B000000/type", 0, make([]byte, 8)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("aborted after %v", d)
+	}
+}
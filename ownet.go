@@ -2,24 +2,62 @@ package ownet
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	pathpkg "path"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// OWNet client, safe for concurrent use. Client created with New in
+// persistent mode has a single connection, so concurrent operations are
+// serialized. Otherwise each operation connects on its own, and client
+// created with NewPool reuses a set of connections, running operations in
+// parallel; client settings are copied to the connection when an operation
+// starts, so changing them neither waits for nor affects operations in
+// progress.
 type OW struct {
-	address string
-	conn    net.Conn
-	hdrbuf  []byte
-	sg      int32
+	network     string
+	address     string
+	dialTimeout time.Duration
+	dialFunc    DialFunc
+	handshake   func(net.Conn) error
+	dialSem     chan struct{} // limits number of dials in progress, if set
+	logger      Logger
+	conn        conn  // shared connection of persistent mode; guarded by the mutex, use acquire
+	pool        *pool // connection pool, nil if not in pool mode
+	stats       stats
+	flights     *flightGroup           // deduplicates concurrent reads, if enabled
+	granted     atomic.Bool            // owserver agreed to keep connection open in last response
+	last        atomic.Pointer[Header] // header of last response
+	version     atomic.Int32           // protocol version of last response
+	settings
 	sync.Mutex
 }
 
-type header struct {
+// Client settings, copied to the connection used by each operation
+type settings struct {
+	sg            int32
+	persistent    bool          // request persistent connections from owserver
+	retries       int           // number of retries on connection failure
+	readSize      int           // initial buffer size for reading whole values
+	timeout       time.Duration // time limit of each request, 0 for none
+	retryDelay    time.Duration // delay before first retry, 0 for none
+	retryMaxDelay time.Duration // cap of delay between retries, 0 for none
+}
+
+// Header of owserver protocol message. In requests Type is the message type,
+// Size is the size of data to read or write and Offset the offset it starts
+// at. In responses Type is the return value, negative on error, and Payload
+// is the length of data following the header.
+type Header struct {
 	Version int32
 	Payload int32
 	Type    int32
@@ -28,7 +66,31 @@ type header struct {
 	Offset  int32
 }
 
-// OWNet message types
+// Version of owserver protocol implemented, sent in Version field of
+// requests
+const ProtocolVersion = 0
+
+// Size of header on the wire
+const headerLen = 24
+
+// Append big-endian encoding of hdr to b.
+func (hdr *Header) append(b []byte) []byte {
+	for _, v := range [...]int32{hdr.Version, hdr.Payload, hdr.Type, hdr.Flags, hdr.Size, hdr.Offset} {
+		b = binary.BigEndian.AppendUint32(b, uint32(v))
+	}
+	return b
+}
+
+// Decode big-endian encoded header from b.
+func (hdr *Header) parse(b []byte) {
+	for i, v := range [...]*int32{&hdr.Version, &hdr.Payload, &hdr.Type, &hdr.Flags, &hdr.Size, &hdr.Offset} {
+		*v = int32(binary.BigEndian.Uint32(b[i*4:]))
+	}
+}
+
+// OWNet message types, sent in Type field of requests. MsgError is not a
+// request, and has nothing to do with errors in responses, which owserver
+// signals by negative Type for all message types.
 const (
 	MsgError       uint32 = iota
 	MsgNop                = iota
@@ -43,205 +105,868 @@ const (
 	MsgGetSlash           = iota
 )
 
-type OWErr int32
+// Regexp matching device identifiers as shown in owserver root directory,
+// in either case
+var DeviceRegex = regexp.MustCompile("(?i)[0-9A-F]{2}\\.[0-9A-F]{12}")
 
-func (e OWErr) Error() string {
-	return fmt.Sprintf("owserver returned error %v", int32(e))
-}
+// Regexp matching path element that is a device identifier in any format
+var deviceElemRegex = regexp.MustCompile("(?i)^[0-9A-F]{2}\\.?[0-9A-F]{12}(\\.?[0-9A-F]{2})?$")
 
-// Regexp matching device identifiers as shown in owserver root directory
-var DeviceRegex = regexp.MustCompile("[0-9A-F]{2}\\.[0-9A-F]{12}")
+// Default owserver TCP port
+const defaultPort = "4304"
 
-// Create a new OWNet client object. Supply owserver address in "host:port" format.
+// Create a new OWNet client object. Supply owserver address in "host:port" format,
+// or path of owserver Unix domain socket, either prefixed with "unix:" or
+// starting with "/" or ".", and options to change default settings, if any.
+// Port may be omitted, defaulting to 4304, also for IPv6 addresses, with or
+// without brackets; empty address means owserver on local host.
 // Connection will be established on first request.
-func New(address string) *OW {
+func New(address string, opts ...Option) *OW {
 	if address == "" {
-		address = "127.0.0.1:4304"
+		address = "127.0.0.1"
 	}
-	return &OW{
-		address: address,
-		sg:      0x102, // some magic flags value
+	network := "tcp"
+	if strings.HasPrefix(address, "unix:") {
+		network, address = "unix", strings.TrimPrefix(address, "unix:")
+	} else if strings.HasPrefix(address, "/") || strings.HasPrefix(address, ".") {
+		network = "unix"
+	} else {
+		address = withDefaultPort(address)
 	}
+	ow := &OW{
+		network:     network,
+		address:     address,
+		dialTimeout: time.Second * 30,
+	}
+	ow.sg = defaultFlags
+	ow.retries = 1
+	ow.readSize = DefaultReadSize
+	for _, opt := range opts {
+		opt(ow)
+	}
+	return ow
 }
 
-func (ow *OW) dial() (err error) {
-	ow.conn, err = net.DialTimeout("tcp", ow.address, time.Second*30)
-	return
+// Dial owserver and perform handshake set by WithHandshake, if any, waiting
+// while the number of connections being established is at limit set by
+// WithMaxDials. Handshake is limited by request timeout, when positive, and
+// dial timeout.
+func (ow *OW) dial(ctx context.Context, timeout time.Duration) (c net.Conn, err error) {
+	if ow.dialSem != nil {
+		select {
+		case ow.dialSem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-ow.dialSem }()
+	}
+	if ow.dialFunc != nil {
+		c, err = ow.dialFunc(ctx, ow.network, ow.address)
+	} else {
+		d := net.Dialer{Timeout: ow.dialTimeout}
+		c, err = d.DialContext(ctx, ow.network, ow.address)
+	}
+	if err != nil || ow.handshake == nil {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	for _, d := range []time.Duration{timeout, ow.dialTimeout} {
+		if d > 0 {
+			if t := time.Now().Add(d); !ok || t.Before(deadline) {
+				deadline, ok = t, true
+			}
+		}
+	}
+	if ok {
+		c.SetDeadline(deadline)
+	}
+	if err = ow.handshake(c); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	c.SetDeadline(time.Time{})
+	return c, nil
 }
 
-// Close connection to owserver.
-func (ow *OW) Close() {
-	if ow.conn != nil {
-		ow.conn.Close()
-		ow.conn = nil
+// Append default port to TCP address missing one. Bare IPv6 address, which
+// may end with a colon itself, is taken as a whole.
+func withDefaultPort(address string) string {
+	if _, port, err := net.SplitHostPort(address); err == nil && port != "" {
+		return address
 	}
+	if net.ParseIP(address) != nil {
+		return net.JoinHostPort(address, defaultPort)
+	}
+	host := strings.TrimSuffix(address, ":")
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return net.JoinHostPort(host, defaultPort)
 }
 
-func (ow *OW) msgRead(payload []byte) (hdr header, n int, err error) {
-	if err = binary.Read(ow.conn, binary.BigEndian, &hdr); err != nil {
-		return
+// Connections of operations of their own, recycled to reuse scratch buffers
+var spareConns = sync.Pool{New: func() interface{} { return new(conn) }}
+
+// Get connection for exclusive use by an operation, with current client
+// settings applied. In pool mode it is an idle or a new pooled connection.
+// In persistent mode it is the shared one, which stays locked until release.
+// Otherwise it is a new one of the operation's own, since connection is not
+// kept between requests anyway, so that operations proceed in parallel.
+// Operations must access connections only this way, so that those in
+// background, like keepalive pings, never race with others.
+func (ow *OW) acquire(ctx context.Context) (*conn, error) {
+	ow.Lock()
+	if ow.pool == nil && ow.persistent {
+		ow.conn.ow = ow
+		ow.conn.settings = ow.settings
+		return &ow.conn, nil
 	}
-	//log.Printf("<- %+v\n", hdr)
-	if hdr.Payload > 0 && len(payload) >= int(hdr.Payload) {
-		n, err = ow.conn.Read(payload[:hdr.Payload])
+	settings := ow.settings
+	ow.Unlock()
+
+	var c *conn
+	if ow.pool != nil {
+		var err error
+		if c, err = ow.pool.get(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		c = spareConns.Get().(*conn)
 	}
-	//log.Printf("<- n:%v payload:%v\n", n, string(payload))
-	return
+	c.ow = ow
+	c.settings = settings
+	return c, nil
 }
 
-func (ow *OW) msgWrite(hdr header, payload []byte) (err error) {
-	if ow.conn == nil {
-		err = ow.dial()
-		if err != nil {
-			return
+// Release connection obtained with acquire.
+func (ow *OW) release(c *conn) {
+	if c.hold {
+		c.hold = false
+		if !c.persistent {
+			c.close()
 		}
 	}
-	var buf bytes.Buffer
-	//log.Printf("-> %+v\n", hdr)
-	//log.Printf("-> payload: %v\n", string(payload))
-	binary.Write(&buf, binary.BigEndian, hdr)
-	buf.Write(payload)
-	for ; buf.Len() > 0 && err == nil; _, err = buf.WriteTo(ow.conn) {
+	switch {
+	case c == &ow.conn:
+		ow.Unlock()
+	case ow.pool != nil:
+		ow.pool.put(c)
+	default:
+		c.close()
+		*c = conn{buf: c.buf[:0]}
+		spareConns.Put(c)
+	}
+}
+
+// Enable or disable persistent connection mode. In persistent mode the
+// connection is kept open between requests as long as owserver agrees to it,
+// and is re-dialed only when it was closed or failed.
+func (ow *OW) SetPersistent(on bool) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.persistent = on
+	if !on {
+		ow.close()
+	}
+}
+
+// Report whether owserver agreed to keep connection open in its last
+// response, as negotiated in persistent mode. It is false before the first
+// response, when not in persistent mode, and when owserver refused.
+func (ow *OW) IsPersistent() bool {
+	return ow.granted.Load()
+}
+
+// Get header of the last response received from owserver, e.g. to diagnose
+// flags negotiation or size mismatches. In pool mode it is the last response
+// received on any connection.
+// Returns header, and false if no response was received yet.
+func (ow *OW) LastResponseHeader() (Header, bool) {
+	if hdr := ow.last.Load(); hdr != nil {
+		return *hdr, true
+	}
+	return Header{}, false
+}
+
+// Default initial size of buffer used to read values of unknown size
+const DefaultReadSize = 256
+
+// Set initial size of buffer used to read values of unknown size, e.g. by
+// GetAttr, same as WithDefaultBufferSize. Non-positive n restores
+// DefaultReadSize.
+func (ow *OW) SetDefaultReadSize(n int) {
+	if n <= 0 {
+		n = DefaultReadSize
+	}
+	ow.Lock()
+	defer ow.Unlock()
+	ow.readSize = n
+}
+
+// Get protocol version owserver responded with last time. Versions newer
+// than ProtocolVersion, which this client may not fully implement, are
+// reported to logger set by WithLogger, if any, when first seen.
+func (ow *OW) ServerProtocolVersion() int32 {
+	return ow.version.Load()
+}
+
+// Set number of times a request is retried on a fresh connection after
+// dialing, sending it or reading response failed. Requests that owserver responded to
+// with an error are never retried, nor are writes that failed after being
+// sent, since owserver may have applied them, unless a reused connection was
+// found closed by owserver before responding. Default is 1.
+func (ow *OW) SetRetries(n int) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.retries = n
+}
+
+// Set time limit of sending each request and receiving response, so that
+// an unresponsive owserver or a half-open connection can not block an
+// operation forever. Request that timed out is retried like after any other
+// connection failure. Default is 0, meaning no limit.
+func (ow *OW) SetTimeout(d time.Duration) {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.timeout = d
+}
+
+// Connect to owserver and check that it responds with a Ping, e.g. to fail
+// fast at startup. Otherwise connection is dialed lazily by the first
+// operation. In persistent mode the connection is kept open for following
+// operations to use, in pool mode it becomes the first pooled one.
+// Returns nil on success, otherwise error, matching ErrConnection if owserver
+// could not be reached.
+func (ow *OW) Connect() error {
+	return ow.ConnectContext(context.Background())
+}
+
+// Same as Connect, but dialing and request are aborted when ctx is done.
+func (ow *OW) ConnectContext(ctx context.Context) error {
+	return ow.PingContext(ctx)
+}
+
+// Close connection to owserver. In pool mode, idle connections are closed
+// immediately and connections in use when they are released.
+// It is safe to call Close concurrently with operations in progress, and
+// repeatedly; following operations connect again.
+func (ow *OW) Close() {
+	ow.Lock()
+	defer ow.Unlock()
+	ow.close()
+}
+
+// Same as Close, but waits for operations in progress to finish first.
+// When ctx is done before that, returns its error; connections still in use
+// are then closed when the operations finish. Operations not in persistent
+// or pool mode use connections of their own, which are not waited for.
+func (ow *OW) CloseContext(ctx context.Context) error {
+	if ow.pool != nil {
+		// taking all slots of the pool waits for connections in use
+		n := 0
+		defer func() {
+			for ; n > 0; n-- {
+				<-ow.pool.sem
+			}
+		}()
+		for ; n < cap(ow.pool.sem); n++ {
+			select {
+			case ow.pool.sem <- struct{}{}:
+			case <-ctx.Done():
+				ow.Close()
+				return contextError(ctx)
+			}
+		}
+		ow.Close()
+		return nil
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		ow.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			ow.close()
+			ow.Unlock()
+		}()
+		return contextError(ctx)
+	}
+	ow.close()
+	ow.Unlock()
+	return nil
+}
+
+// Same as Close, but caller must hold the lock.
+func (ow *OW) close() {
+	if ow.pool == nil {
+		ow.conn.close()
+	} else {
+		ow.pool.close()
 	}
-	return
 }
 
 // Get listing of specified directory.
 // Returns array with directory items names and error if any.
 func (ow *OW) Dir(path string) (items []string, err error) {
-	ow.Lock()
-	defer ow.Unlock()
+	return ow.DirContext(context.Background(), path)
+}
+
+// Same as Dir, but request is aborted when ctx is done.
+func (ow *OW) DirContext(ctx context.Context, path string) (items []string, err error) {
+	return ow.dir(ctx, path, MsgDirAll)
+}
 
-	err = ow.dial()
+// Directory listing request of type msgType, either MsgDirAll or
+// MsgDirAllSlash. The latter appends slash to items that are directories.
+func (ow *OW) dir(ctx context.Context, path string, msgType int32) (items []string, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	defer ow.Close()
+	defer ow.release(c)
+	return c.dir(ctx, path, msgType)
+}
 
-	ret := make([]byte, 4096, 4096)
-	hdr := header{
-		Version: 0,
-		Payload: int32(len(path) + 1),
-		Type:    MsgDirAll,
-		Flags:   ow.sg,
-		Size:    int32(len(ret)),
+// Get names of items in directory at path, that is the last components of
+// their paths, like "temperature" for "/28.A1B2C3000000/temperature".
+// owserver always lists full paths, which Dir returns.
+// Returns array of names and error if any.
+func (ow *OW) DirNames(path string) ([]string, error) {
+	items, err := ow.Dir(path)
+	if err != nil {
+		return nil, err
 	}
-	err = ow.msgWrite(hdr, append([]byte(path), 0))
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = pathpkg.Base(item)
+	}
+	return names, nil
+}
+
+// Get directory listing of path by sending MsgDirAllSlash request, to which
+// owserver responds with slash appended to items that are directories. It
+// tells subdirectories from attributes without further requests, e.g. to
+// walk a device with both a subdirectory and an attribute of similar name,
+// like "humidity/" and "humidity" of some humidity sensors.
+// Returns array of items and error if any.
+func (ow *OW) DirSlash(path string) ([]string, error) {
+	return ow.dir(context.Background(), path, MsgDirAllSlash)
+}
+
+// Get subdirectories of path, like devices, bus.N and settings, skipping
+// attributes, e.g. to find where to descend when walking the tree. Like
+// DirSlash, it takes a single request.
+// Returns array of full paths of subdirectories, without trailing slash, and
+// error if any.
+func (ow *OW) ListDirs(path string) ([]string, error) {
+	items, err := ow.DirSlash(path)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(items))
+	for _, item := range items {
+		if strings.HasSuffix(item, "/") {
+			dirs = append(dirs, strings.TrimSuffix(item, "/"))
+		}
+	}
+	return dirs, nil
+}
+
+// Get contents of owserver path by sending MsgGet request, leaving it to
+// owserver to decide whether path is a directory or a file. Unlike Dir, which
+// fails on a file, and Read, which fails on a directory, it succeeds on both:
+// the result is comma-separated listing, as returned to MsgDirAll, if path is
+// a directory, and value like that of ReadAll otherwise.
+// Returns raw contents and error if any.
+func (ow *OW) Get(path string) ([]byte, error) {
+	return ow.get(context.Background(), path, MsgGet)
+}
+
+// Get contents of owserver path by sending MsgGetSlash request: directory
+// listing like that of DirSlash, comma-separated, if path is a directory, and
+// value like that of ReadAll otherwise.
+// Returns raw contents and error if any.
+func (ow *OW) GetSlash(path string) ([]byte, error) {
+	return ow.get(context.Background(), path, MsgGetSlash)
+}
+
+// Send request of type msgType for path, followed by data, with current
+// flags, offset and size set in the header, and read the response, e.g. to
+// use message types not supported by other methods. Path is sent as is,
+// without normalization. For requests returning data, size is the size of
+// the buffer for it: response payload exceeding it is discarded, which
+// Payload of the returned header then tells.
+// Returns response header, payload and error if any, including owserver
+// error indicated by negative Type.
+func (ow *OW) RawRequest(msgType uint32, path string, data []byte, offset, size int) (Header, []byte, error) {
+	return ow.RawRequestContext(context.Background(), msgType, path, data, offset, size)
+}
+
+// Same as RawRequest, but request is aborted when ctx is done.
+func (ow *OW) RawRequestContext(ctx context.Context, msgType uint32, path string, data []byte, offset, size int) (hdr Header, ret []byte, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	hdr, _, err = ow.msgRead(ret)
+	defer ow.release(c)
+	return c.raw(ctx, int32(msgType), path, data, offset, size)
+}
+
+func (ow *OW) get(ctx context.Context, path string, msgType int32) (data []byte, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	if hdr.Type != 0 {
-		return nil, OWErr(hdr.Type)
+	defer ow.release(c)
+	return c.get(ctx, path, msgType)
+}
+
+// Normalize owserver path: make it absolute and collapse repeated slashes,
+// as well as "." and ".." elements, and convert device identifiers to upper
+// case. Paths with embedded NUL, which would terminate them on the wire, are
+// rejected.
+// Returns cleaned path and ErrInvalidPath if path is not valid.
+func CleanPath(path string) (string, error) {
+	if strings.IndexByte(path, 0) >= 0 {
+		return "", ErrInvalidPath
+	}
+	elems := strings.Split(pathpkg.Clean("/"+path), "/")
+	for i, elem := range elems {
+		if deviceElemRegex.MatchString(elem) {
+			elems[i] = strings.ToUpper(elem)
+		}
+	}
+	return strings.Join(elems, "/"), nil
+}
+
+// Split directory listing into trimmed non-empty items. owserver separates
+// items with commas, but some builds and text views use newlines, so both
+// are accepted.
+func splitDir(ret []byte) (items []string) {
+	sep := func(r rune) bool { return r == ',' || r == '\n' }
+	for _, item := range strings.FieldsFunc(string(ret), sep) {
+		item = strings.TrimSpace(strings.Trim(item, "\x00"))
+		if item != "" {
+			items = append(items, item)
+		}
 	}
-	return strings.Split(string(ret), ","), err
+	return
 }
 
 // Read owserver file with path starting from offset into data.
+// Reading nothing into non-empty data, at or past the end of file, returns
+// io.EOF, as io.Reader does.
+// In case owserver responds with more data than requested, data is filled
+// and BufferTooSmallError reporting the response size is returned.
 // Returns number of read bytes and error if any.
 func (ow *OW) Read(path string, offset int, data []byte) (n int, err error) {
-	ow.Lock()
-	defer ow.Unlock()
+	return ow.ReadContext(context.Background(), path, offset, data)
+}
+
+// Same as Read, but request is aborted when ctx is done.
+func (ow *OW) ReadContext(ctx context.Context, path string, offset int, data []byte) (n int, err error) {
+	return ow.read(ctx, path, offset, data, 0)
+}
+
+// Same as Read, but value is read from the device bypassing owserver cache,
+// regardless of SetUncached setting.
+func (ow *OW) ReadUncached(path string, offset int, data []byte) (n int, err error) {
+	return ow.read(context.Background(), path, offset, data, flagUncached)
+}
 
-	err = ow.dial()
+// Read request with additional flags set.
+func (ow *OW) read(ctx context.Context, path string, offset int, data []byte, flags int32) (n int, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	defer ow.Close()
-
-	hdr := header{
-		Version: 0,
-		Payload: int32(len(path) + 1),
-		Type:    MsgRead,
-		Flags:   ow.sg,
-		Size:    int32(len(data)),
-		Offset:  int32(offset),
+	defer ow.release(c)
+	n, err = c.read(ctx, path, offset, data, flags)
+	if err == nil && n == 0 && len(data) > 0 {
+		err = io.EOF
 	}
-	err = ow.msgWrite(hdr, append([]byte(path), 0))
+	return
+}
+
+// Write data to owserver file at path starting from offset. Data must not be
+// empty: owserver attributes are set by writing a value, e.g. counters are
+// reset by writing "0", so writing nothing is rejected with ErrEmptyWrite
+// without sending a request.
+// Returns nil on success, otherwise error.
+func (ow *OW) Write(path string, offset int, data []byte) (err error) {
+	return ow.WriteContext(context.Background(), path, offset, data)
+}
+
+// Same as Write, but request is aborted when ctx is done.
+func (ow *OW) WriteContext(ctx context.Context, path string, offset int, data []byte) (err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	hdr, n, err = ow.msgRead(data)
+	defer ow.release(c)
+	return c.write(ctx, path, offset, data)
+}
+
+// Read whole owserver file at path, regardless of its size. Buffer is sized
+// according to Size, and reading continues at increasing offsets while
+// owserver returns as much data as requested, up to the reported size.
+// Returns file contents and error if any.
+func (ow *OW) ReadAll(path string) ([]byte, error) {
+	ctx := context.Background()
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ow.release(c)
+	c.hold = true
+	size, err := c.size(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.readWhole(ctx, path, size)
+}
+
+// Read up to size bytes of owserver file at path starting from offset, e.g.
+// a page of a paged read, along with total size of the file, which tells
+// whether more data remains after the page. Response to read request does
+// not carry total size, so it is taken from Size request made over the same
+// connection first.
+// Returns data read, total size and error if any.
+func (ow *OW) ReadFull(path string, offset, size int) (data []byte, total int, err error) {
+	ctx := context.Background()
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	if hdr.Type < 0 {
-		err = OWErr(hdr.Type)
+	defer ow.release(c)
+	c.hold = true
+	if total, err = c.size(ctx, path); err != nil {
 		return
 	}
-	return
+	if rest := total - offset; rest < size {
+		size = rest
+	}
+	if size <= 0 {
+		return []byte{}, total, nil
+	}
+	data = make([]byte, size, size)
+	n, err := c.read(ctx, path, offset, data, 0)
+	if err != nil {
+		return nil, total, err
+	}
+	return data[:n], total, nil
 }
 
-// Write data to owserver file at path starting from offset.
-// Returns nil on success, otherwise error.
-func (ow *OW) Write(path string, offset int, data []byte) (err error) {
-	ow.Lock()
-	defer ow.Unlock()
-
-	err = ow.dial()
+// Read whole owserver file at path in chunks of chunkSize bytes, e.g. large
+// memory of EEPROM devices in memory-constrained environments. Reading
+// advances offset by chunk until the size reported by Size is reached, so
+// that no empty read follows the last chunk, or until a short read.
+// Returns file contents and error if any.
+func (ow *OW) ReadChunked(path string, chunkSize int) ([]byte, error) {
+	if chunkSize <= 0 {
+		return nil, opError("read", path, fmt.Errorf("ownet: invalid chunk size %d", chunkSize))
+	}
+	ctx := context.Background()
+	c, err := ow.acquire(ctx)
 	if err != nil {
-		return
+		return nil, err
+	}
+	defer ow.release(c)
+	c.hold = true
+	size, err := c.size(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, size)
+	buf := make([]byte, chunkSize, chunkSize)
+	for len(out) < size {
+		chunk := buf
+		if rest := size - len(out); rest < len(chunk) {
+			chunk = chunk[:rest]
+		}
+		n, err := c.read(ctx, path, len(out), chunk, 0)
+		if errors.Is(err, ErrBufferTooSmall) {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk[:n]...)
+		if n < len(chunk) {
+			break
+		}
 	}
-	defer ow.Close()
+	return out, nil
+}
 
-	hdr := header{
-		Version: 0,
-		Payload: int32(len(path) + 1 + len(data)),
-		Type:    MsgWrite,
-		Flags:   ow.sg,
-		Size:    int32(len(data)),
-		Offset:  int32(offset),
+// Read whole owserver file at path like ReadAll, appending its contents to
+// buf, which grows as needed. Nothing is appended if reading fails.
+// Returns number of bytes appended and error if any.
+func (ow *OW) ReadInto(path string, buf *bytes.Buffer) (int, error) {
+	data, err := ow.ReadAll(path)
+	if err != nil {
+		return 0, err
 	}
-	err = ow.msgWrite(hdr, append(append([]byte(path), 0), data...))
+	return buf.Write(data)
+}
+
+// Get size of owserver file at path by sending MsgSize request.
+// Returned value is the size reported by owserver, which is not necessarily
+// the number of bytes a subsequent Read will return.
+// Returns size and error if any.
+func (ow *OW) Size(path string) (int, error) {
+	return ow.SizeContext(context.Background(), path)
+}
+
+// Same as Size, but request is aborted when ctx is done.
+func (ow *OW) SizeContext(ctx context.Context, path string) (size int, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	hdr, _, err = ow.msgRead(nil)
+	defer ow.release(c)
+	return c.size(ctx, path)
+}
+
+// Check presence of device at path by sending MsgPresence request.
+// Returns true if device is present, false if owserver reports that it is
+// not, and error if presence could not be determined.
+func (ow *OW) Presence(path string) (bool, error) {
+	return ow.PresenceContext(context.Background(), path)
+}
+
+// Same as Presence, but request is aborted when ctx is done.
+func (ow *OW) PresenceContext(ctx context.Context, path string) (present bool, err error) {
+	c, err := ow.acquire(ctx)
 	if err != nil {
 		return
 	}
-	if hdr.Type < 0 {
-		err = OWErr(hdr.Type)
-		return
+	defer ow.release(c)
+	return c.presence(ctx, path)
+}
+
+// Check whether owserver path, either a device, a directory or a file,
+// exists. Same as Presence, which owserver supports for any path.
+// Returns true if path exists, false if it does not, and error if existence
+// could not be determined.
+func (ow *OW) Exists(path string) (bool, error) {
+	return ow.Presence(path)
+}
+
+// Get list of present devices on the bus. Devices identified with DeviceRegex,
+// or with regexp matching the selected display format if it is not f.i.
+// Returns array of device identifiers and error if any.
+func (ow *OW) ListDevices() ([]string, error) {
+	return ow.listDevices("/")
+}
+
+// Same as ListDevices, but listing is aborted when ctx is done. Devices are
+// listed one at a time with MsgDir request, so if listing is aborted or
+// fails partway, devices collected so far are returned along with the error.
+func (ow *OW) ListDevicesContext(ctx context.Context) ([]string, error) {
+	re := ow.DisplayFormat().regex()
+	devs := []string{}
+	err := ow.dirEach(ctx, "/", func(item string) error {
+		if dev := re.FindString(pathpkg.Base(item)); dev != "" {
+			devs = append(devs, dev)
+		}
+		return nil
+	})
+	return devs, err
+}
+
+// Get list of devices in alarm state, as found by owserver alarm search.
+// Devices are identified same as by ListDevices.
+// Returns array of device identifiers, empty if there are no alarms, and
+// error if any.
+func (ow *OW) ListAlarmingDevices() ([]string, error) {
+	return ow.listDevices("/alarm")
+}
+
+// Get list of present devices of any of the given families, like 0x28 for
+// DS18B20. Devices are identified same as by ListDevices.
+// Returns array of device identifiers and error if any.
+func (ow *OW) ListDevicesByFamily(families ...byte) ([]string, error) {
+	devs, err := ow.ListDevices()
+	if err != nil {
+		return nil, err
 	}
-	return
+	matching := []string{}
+	for _, dev := range devs {
+		id, err := ParseDeviceID(dev)
+		if err != nil {
+			continue
+		}
+		for _, family := range families {
+			if id.Family == family {
+				matching = append(matching, dev)
+				break
+			}
+		}
+	}
+	return matching, nil
+}
+
+// Regexp matching names of bus directories, one per physical adapter
+var busRegex = regexp.MustCompile(`^bus\.[0-9]+$`)
+
+// Get list of buses, one per physical adapter of owserver, like "bus.0".
+// Returns array of bus names and error if any.
+func (ow *OW) ListBuses() ([]string, error) {
+	dir, err := ow.Dir("/")
+	if err != nil {
+		return nil, err
+	}
+	buses := []string{}
+	for _, item := range dir {
+		if name := pathpkg.Base(item); busRegex.MatchString(name) {
+			buses = append(buses, name)
+		}
+	}
+	return buses, nil
 }
 
-// Get list of present devices on the bus. Devices identified with DeviceRegex.
+// Get list of devices present on bus n, that is on n-th physical adapter.
+// Devices are identified same as by ListDevices.
 // Returns array of device identifiers and error if any.
-func (ow *OW) ListDevices() (devs []string, err error) {
-	var dir []string
-	dir, err = ow.Dir("/")
+func (ow *OW) DevicesOnBus(n int) ([]string, error) {
+	return ow.listDevices(fmt.Sprintf("/bus.%d", n))
+}
+
+// Get list of devices in directory at path.
+func (ow *OW) listDevices(path string) ([]string, error) {
+	re := ow.DisplayFormat().regex()
+	dir, err := ow.Dir(path)
 	if err != nil {
-		return
+		return nil, err
 	}
+	devs := []string{}
 	for _, item := range dir {
-		dev := DeviceRegex.FindString(item)
+		dev := re.FindString(pathpkg.Base(item))
 		if dev != "" {
 			devs = append(devs, dev)
 		}
 	}
+	return devs, nil
+}
+
+// Maximum depth of nested DS2409 coupler branches descended into by
+// ListDevicesRecursive.
+const MaxBranchDepth = 8
+
+// Get list of present devices on the bus including devices on branches of
+// DS2409 microlan couplers (family 1F), which are descended into up to
+// MaxBranchDepth levels. Each device is reported once.
+// Returns array of full device paths, e.g. "/1F.0D2A05000000/main/28.A1B2C3000000",
+// and error if any.
+func (ow *OW) ListDevicesRecursive() (devs []string, err error) {
+	re := ow.DisplayFormat().regex()
+	seen := make(map[string]bool)
+	var list func(dir string, depth int) error
+	list = func(dir string, depth int) error {
+		items, err := ow.Dir(dir)
+		if depth > 0 && errors.Is(err, ErrNotFound) {
+			return nil // branch without devices
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			// owserver lists full paths, which contain coupler IDs
+			dev := re.FindString(pathpkg.Base(item))
+			if dev == "" || seen[dev] {
+				continue
+			}
+			seen[dev] = true
+			p := pathpkg.Join(dir, dev)
+			devs = append(devs, p)
+			if !strings.HasPrefix(strings.ToUpper(dev), "1F") || depth >= MaxBranchDepth {
+				continue
+			}
+			for _, branch := range []string{"main", "aux"} {
+				if err := list(pathpkg.Join(p, branch), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = list("/", 0)
 	return
 }
 
 // Get value of attribute attr of the device.
-// Returns attribute value and error if any.
+// Returns complete attribute value and error if any.
 func (ow *OW) GetAttr(device, attr string) (string, error) {
-	buf := make([]byte, 16, 16)
-	if n, err := ow.Read(fmt.Sprintf("/%s/%s", device, attr), 0, buf); err != nil {
+	return ow.ReadString(fmt.Sprintf("/%s/%s", device, attr))
+}
+
+// Get raw value of attribute attr of the device, without any conversion or
+// trimming, for attributes holding binary data rather than text, like
+// "memory" and "pages/page.N" of memory devices such as DS2431 and DS2433,
+// or "pages/page.N" of DS2438.
+// Returns complete attribute value and error if any.
+func (ow *OW) GetAttrBytes(device, attr string) ([]byte, error) {
+	ctx := context.Background()
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ow.release(c)
+	return c.readWhole(ctx, fmt.Sprintf("/%s/%s", device, attr), 0)
+}
+
+// Read complete value of owserver file at arbitrary path, with trailing NULs
+// trimmed.
+// Returns value and error if any.
+func (ow *OW) ReadString(path string) (string, error) {
+	return ow.readStringContext(context.Background(), path)
+}
+
+func (ow *OW) readStringContext(ctx context.Context, path string) (string, error) {
+	v, err := ow.readWhole(ctx, path)
+	if err != nil {
 		return "", err
-	} else {
-		return string(buf[:n]), nil
 	}
+	return strings.TrimRight(string(v), "\x00"), nil
+}
+
+// Read complete value of owserver file at path, sharing the result with
+// concurrent reads of the same path if enabled with WithSingleFlight. The
+// result may thus be shared and must not be modified.
+func (ow *OW) readWhole(ctx context.Context, path string) ([]byte, error) {
+	if ow.flights != nil {
+		return ow.flights.do(path, func() ([]byte, error) {
+			return ow.readWholeOnce(ctx, path)
+		})
+	}
+	return ow.readWholeOnce(ctx, path)
+}
+
+func (ow *OW) readWholeOnce(ctx context.Context, path string) ([]byte, error) {
+	c, err := ow.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ow.release(c)
+	return c.readWhole(ctx, path, 0)
 }
 
 // Set value of attribute attr of the device to value.
 // Returns nil on success, error otherwise.
 func (ow *OW) SetAttr(device, attr, value string) error {
-	return ow.Write(fmt.Sprintf("/%s/%s", device, attr), 0, []byte(value))
+	return ow.WriteString(fmt.Sprintf("/%s/%s", device, attr), value)
+}
+
+// Set value of owserver file at arbitrary path to value.
+// Returns nil on success, error otherwise.
+func (ow *OW) WriteString(path, value string) error {
+	return ow.Write(path, 0, []byte(value))
 }
 
 // Get type of the device. Equal to GetAttr(device, "type).
@@ -251,3 +976,9 @@ func (ow *OW) SetAttr(device, attr, value string) error {
 func (ow *OW) GetType(device string) (string, error) {
 	return ow.GetAttr(device, "type")
 }
+
+// Get temperature of the device, in the scale set by SetTemperatureScale.
+// Returns temperature and error if any.
+func (ow *OW) Temperature(device string) (float64, error) {
+	return ow.ReadFloat(fmt.Sprintf("/%s/temperature", device))
+}
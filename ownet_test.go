@@ -1,50 +1,891 @@
 package ownet
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
-const (
-	srv  = "192.168.0.10:4304"
-	attr = "/3A.BEE71B000000/PIO.B"
-)
+const attr = "/3A.BEE71B000000/PIO.B"
+
+// Fake owserver with a DS2413 and a DS18B20 on the bus
+func newTestServer(t *testing.T) *fakeServer {
+	return newFakeServer(t, map[string]string{
+		"/3A.BEE71B000000/type":             "DS2413",
+		"/3A.BEE71B000000/PIO.A":            "0",
+		"/3A.BEE71B000000/PIO.B":            "0",
+		"/28.A1B2C3000000/type":             "DS18B20",
+		"/28.A1B2C3000000/temperature":      "     21.5",
+		"/settings/units/temperature_scale": "C",
+	})
+}
 
 func TestDir(t *testing.T) {
-	ow := New(srv)
+	ow := New(newTestServer(t).addr())
 
 	dir, err := ow.Dir("/")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("dir: %+v\n", dir)
+	want := []string{"/28.A1B2C3000000", "/3A.BEE71B000000", "/settings"}
+	if !reflect.DeepEqual(dir, want) {
+		t.Errorf("dir: got %q, want %q", dir, want)
+	}
 }
 
 func TestRead(t *testing.T) {
-	ow := New(srv)
+	ow := New(newTestServer(t).addr())
 
 	buf := make([]byte, 16, 16)
 	n, err := ow.Read(attr, 0, buf)
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("data: %+v\n", buf[:n])
+	if string(buf[:n]) != "0" {
+		t.Errorf("data: got %q, want %q", buf[:n], "0")
+	}
 }
 
 func TestWrite(t *testing.T) {
-	ow := New(srv)
+	s := newTestServer(t)
+	ow := New(s.addr())
 
 	err := ow.Write(attr, 0, []byte("1"))
 	if err != nil {
 		t.Fatal(err)
 	}
+	if v := s.file(attr); v != "1" {
+		t.Errorf("value: got %q, want %q", v, "1")
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	for _, data := range [][]byte{nil, {}} {
+		if err := ow.Write(attr, 0, data); !errors.Is(err, ErrEmptyWrite) {
+			t.Errorf("write %q: got %v, want ErrEmptyWrite", data, err)
+		}
+	}
+	if err := ow.SetAttr("3A.BEE71B000000", "PIO.B", ""); !errors.Is(err, ErrEmptyWrite) {
+		t.Errorf("SetAttr empty: got %v, want ErrEmptyWrite", err)
+	}
+	if n := s.dialCount(); n != 0 {
+		t.Errorf("dialed %d connections, want 0", n)
+	}
 }
 
 func TestListDevices(t *testing.T) {
-	ow := New(srv)
+	ow := New(newTestServer(t).addr())
 
 	devs, err := ow.ListDevices()
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("devs: %+v\n", devs)
+	want := []string{"28.A1B2C3000000", "3A.BEE71B000000"}
+	if !reflect.DeepEqual(devs, want) {
+		t.Errorf("devs: got %q, want %q", devs, want)
+	}
+}
+
+func TestSplitDir(t *testing.T) {
+	got := splitDir([]byte("/3A.BEE71B000000, /bus.0,,/settings\x00"))
+	want := []string{"/3A.BEE71B000000", "/bus.0", "/settings"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got = splitDir([]byte("/3A.BEE71B000000\n/bus.0\n\n/settings\n"))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newlines: got %q, want %q", got, want)
+	}
+}
+
+func TestListDirs(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	dirs, err := ow.ListDirs("/")
+	want := []string{"/28.A1B2C3000000", "/3A.BEE71B000000", "/settings"}
+	if err != nil || !reflect.DeepEqual(dirs, want) {
+		t.Errorf("root: got %q, %v, want %q", dirs, err, want)
+	}
+	dirs, err = ow.ListDirs("/28.A1B2C3000000")
+	if err != nil || dirs == nil || len(dirs) != 0 {
+		t.Errorf("device: got %q, %v, want none", dirs, err)
+	}
+}
+
+func TestDirNames(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	names, err := ow.DirNames("/28.A1B2C3000000")
+	if err != nil || !reflect.DeepEqual(names, []string{"temperature", "type"}) {
+		t.Errorf("got %q, %v", names, err)
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"28.000000000001/type", "/28.000000000001/type"},
+		{"//28.000000000001//type", "/28.000000000001/type"},
+		{"/bus.0/", "/bus.0"},
+	}
+	for _, tt := range tests {
+		if got, err := CleanPath(tt.path); err != nil || got != tt.want {
+			t.Errorf("CleanPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := CleanPath("/type\x00/x"); err != ErrInvalidPath {
+		t.Errorf("embedded NUL: got %v, want ErrInvalidPath", err)
+	}
+
+	s := newTestServer(t)
+	ow := New(s.addr())
+	if typ, err := ow.GetType("/28.A1B2C3000000/"); err != nil || typ != "DS18B20" {
+		t.Errorf("device with slashes: got %q, %v", typ, err)
+	}
+	if _, err := ow.Read("/type\x00", 0, make([]byte, 8)); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("read embedded NUL: got %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	ow := New(s.addr())
+
+	for i := 0; i < 3; i++ {
+		if _, err := ow.GetType("28.000000000001"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.dialCount(); n != 3 {
+		t.Errorf("non-persistent: dialed %d connections, want 3", n)
+	}
+
+	ow.SetPersistent(true)
+	defer ow.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := ow.GetType("28.000000000001"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.dialCount(); n != 4 {
+		t.Errorf("persistent: dialed %d connections, want 4", n)
+	}
+}
+
+func TestIsPersistent(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+	defer ow.Close()
+
+	if ow.IsPersistent() {
+		t.Error("persistent before first response")
+	}
+	if _, err := ow.ReadBatch([]string{"/28.A1B2C3000000/type", "/3A.BEE71B000000/type"}); err != nil {
+		t.Fatal(err)
+	}
+	if ow.IsPersistent() {
+		t.Error("persistent after batch outside of persistent mode")
+	}
+	ow.SetPersistent(true)
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if !ow.IsPersistent() {
+		t.Error("not persistent after owserver agreed")
+	}
+
+	s.mu.Lock()
+	s.persist = false
+	s.mu.Unlock()
+	ow.Close()
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if ow.IsPersistent() {
+		t.Error("persistent after owserver refused")
+	}
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ow.GetType("28.A1B2C3000000")
+		}()
+		go func() {
+			defer wg.Done()
+			ow.Close()
+		}()
+	}
+	wg.Wait()
+	ow.Close()
+	ow.Close()
+	if typ, err := ow.GetType("28.A1B2C3000000"); err != nil || typ != "DS18B20" {
+		t.Errorf("after Close: got %q, %v", typ, err)
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	s := newTestServer(t)
+	s.delay = 100 * time.Millisecond
+	ow := New(s.addr(), WithPersistent())
+
+	done := make(chan error)
+	go func() {
+		_, err := ow.GetType("28.A1B2C3000000")
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ow.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("in progress: got %v, want deadline exceeded", err)
+	}
+	if err := ow.CloseContext(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("operation in progress failed: %v", err)
+	}
+}
+
+func TestLastResponseHeader(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	if _, ok := ow.LastResponseHeader(); ok {
+		t.Error("header before first response")
+	}
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if hdr, ok := ow.LastResponseHeader(); !ok || hdr.Type != 7 || hdr.Payload != 7 {
+		t.Errorf("read: got %+v, %v", hdr, ok)
+	}
+	ow.GetType("28.000000000000")
+	if hdr, _ := ow.LastResponseHeader(); hdr.Type != -2 {
+		t.Errorf("error: got %+v", hdr)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	ow := New(s.addr())
+	ow.SetPersistent(true)
+	defer ow.Close()
+
+	s.dropNext(1)
+	if typ, err := ow.GetType("28.000000000001"); err != nil || typ != "DS18B20" {
+		t.Fatalf("GetType: got %q, %v", typ, err)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+
+	s.dropNext(2)
+	_, err := ow.GetType("28.000000000001")
+	if !errors.Is(err, ErrConnection) {
+		t.Errorf("got %v, want connection error", err)
+	}
+}
+
+func TestReconnectFlags(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent(), WithTemperatureScale(Fahrenheit))
+	defer ow.Close()
+	ow.SetUncached(true)
+
+	if _, err := ow.Temperature("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	s.closeConns()
+	if _, err := ow.Temperature("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Fatalf("dialed %d connections, want 2", n)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.requests[0].Flags
+	if first&flagPersistence == 0 || first&flagUncached == 0 || TemperatureScale(first&flagTempScaleMask>>flagTempScaleShift) != Fahrenheit {
+		t.Errorf("flags before reconnect: %#x", first)
+	}
+	for _, hdr := range s.requests[1:] {
+		if hdr.Flags != first {
+			t.Errorf("flags after reconnect: got %#x, want %#x", hdr.Flags, first)
+		}
+	}
+}
+
+func TestRetryWrite(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		attr: "0",
+	})
+	ow := New(s.addr())
+
+	s.dropNext(1)
+	if err := ow.Write(attr, 0, []byte("1")); !errors.Is(err, ErrConnection) {
+		t.Errorf("got %v, want connection error", err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestRetryWriteStale(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	// owserver closes idle connection, which is found out by the write
+	s.closeConns()
+	if err := ow.Write(attr, 0, []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file(attr); v != "1" {
+		t.Errorf("value: got %q, want %q", v, "1")
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+}
+
+func TestReadContextDeadline(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	s.delay = time.Second
+	ow := New(s.addr())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	buf := make([]byte, 16)
+	_, err := ow.ReadContext(ctx, "/28.000000000001/type", 0, buf)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	s.delay = time.Second
+	ow := New(s.addr(), WithTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	buf := make([]byte, 16)
+	_, err := ow.Read("/28.000000000001/type", 0, buf)
+	if !errors.Is(err, ErrConnection) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("got %v, want connection timeout", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("timed out after %v", d)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2 with retry", n)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	long := strings.Repeat("0123456789", 10)
+	s := newFakeServer(t, map[string]string{
+		"/system/process/version": long,
+	})
+	ow := New(s.addr(), WithDefaultBufferSize(16))
+	ow.SetPersistent(true)
+	defer ow.Close()
+
+	data, err := ow.ReadAll("/system/process/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != long {
+		t.Errorf("got %q, want %q", data, long)
+	}
+	s.mu.Lock()
+	reads := 0
+	for _, hdr := range s.requests {
+		if hdr.Type == MsgRead {
+			reads++
+		}
+	}
+	s.mu.Unlock()
+	if reads != 1 {
+		t.Errorf("sent %d read requests, want 1", reads)
+	}
+
+	v, err := ow.GetAttr("system/process", "version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != long {
+		t.Errorf("GetAttr: got %q, want %q", v, long)
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	var buf bytes.Buffer
+	buf.WriteString("type=")
+	n, err := ow.ReadInto("/28.A1B2C3000000/type", &buf)
+	if err != nil || n != 7 || buf.String() != "type=DS18B20" {
+		t.Errorf("got %d, %q, %v", n, buf.String(), err)
+	}
+	if n, err := ow.ReadInto("/28.A1B2C3000000/humidity", &buf); err == nil || n != 0 || buf.Len() != 12 {
+		t.Errorf("missing file: got %d, %q, %v", n, buf.String(), err)
+	}
+}
+
+func TestReadLargePayload(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	s := newFakeServer(t, map[string]string{
+		"/long": long,
+		"/type": "DS18B20",
+	})
+	ow := New(s.addr())
+	ow.SetPersistent(true)
+	defer ow.Close()
+
+	// request more than fits into the buffer
+	c, err := ow.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := Header{Payload: 6, Type: MsgRead, Flags: c.sg, Size: 200}
+	buf := make([]byte, 10)
+	rhdr, n, err := c.request(context.Background(), hdr, []byte("/long\x00"), buf)
+	ow.release(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 || rhdr.Payload != 100 {
+		t.Errorf("got n=%d payload=%d, want n=10 payload=100", n, rhdr.Payload)
+	}
+	if typ, err := ow.ReadAll("/type"); err != nil || string(typ) != "DS18B20" {
+		t.Errorf("next read on connection: got %q, %v", typ, err)
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestListDevicesRecursive(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/1F.0D2A05000000/type":                                          "DS2409",
+		"/1F.0D2A05000000/main/28.A1B2C3000000/type":                     "DS18B20",
+		"/1F.0D2A05000000/aux/1F.0E2A05000000/type":                      "DS2409",
+		"/1F.0D2A05000000/aux/1F.0E2A05000000/main/28.A1B2C4000000/type": "DS18B20",
+		"/3A.BEE71B000000/type":                                          "DS2413",
+	})
+	ow := New(s.addr())
+
+	devs, err := ow.ListDevicesRecursive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/1F.0D2A05000000",
+		"/1F.0D2A05000000/main/28.A1B2C3000000",
+		"/1F.0D2A05000000/aux/1F.0E2A05000000",
+		"/1F.0D2A05000000/aux/1F.0E2A05000000/main/28.A1B2C4000000",
+		"/3A.BEE71B000000",
+	}
+	if !reflect.DeepEqual(devs, want) {
+		t.Errorf("got %q, want %q", devs, want)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "owserver.sock")
+	newFakeServerOn(t, "unix", sock, map[string]string{
+		"/28.A1B2C3000000/type": "DS18B20",
+	})
+
+	for _, address := range []string{sock, "unix:" + sock} {
+		ow := New(address)
+		if typ, err := ow.GetType("28.A1B2C3000000"); err != nil || typ != "DS18B20" {
+			t.Errorf("%s: got %q, %v", address, typ, err)
+		}
+	}
+}
+
+func TestDefaultPort(t *testing.T) {
+	for address, want := range map[string]string{
+		"":                "127.0.0.1:4304",
+		"192.168.0.10":    "192.168.0.10:4304",
+		"owserver:3000":   "owserver:3000",
+		"owserver:":       "owserver:4304",
+		"fe80::1":         "[fe80::1]:4304",
+		"[fe80::1]":       "[fe80::1]:4304",
+		"[fe80::1]:3000":  "[fe80::1]:3000",
+		"fe80::":          "[fe80::]:4304",
+		"::":              "[::]:4304",
+		"2001:db8::":      "[2001:db8::]:4304",
+		"[2001:db8::]:":   "[2001:db8::]:4304",
+		"unix:/run/owsrv": "/run/owsrv",
+	} {
+		if got := New(address).address; got != want {
+			t.Errorf("%q: got %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestListAlarmingDevices(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/alarm/":                      "",
+		"/28.A1B2C3000000/temperature": "21.5",
+	})
+	ow := New(s.addr())
+
+	devs, err := ow.ListAlarmingDevices()
+	if err != nil || devs == nil || len(devs) != 0 {
+		t.Errorf("no alarms: got %q, %v", devs, err)
+	}
+
+	s.mu.Lock()
+	s.files["/alarm/28.A1B2C3000000/temperature"] = "21.5"
+	s.mu.Unlock()
+	devs, err = ow.ListAlarmingDevices()
+	if err != nil || !reflect.DeepEqual(devs, []string{"28.A1B2C3000000"}) {
+		t.Errorf("alarm: got %q, %v", devs, err)
+	}
+}
+
+func TestListDevicesLarge(t *testing.T) {
+	files := make(map[string]string)
+	var want []string
+	for i := 0; i < 500; i++ {
+		id := fmt.Sprintf("28.%012X", i)
+		files["/"+id+"/type"] = "DS18B20"
+		want = append(want, id)
+	}
+	s := newFakeServer(t, files)
+	ow := New(s.addr())
+
+	devs, err := ow.ListDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devs, want) {
+		t.Errorf("got %d devices, want %d", len(devs), len(want))
+	}
+}
+
+func TestDeviceIDCase(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	if v, err := ow.GetAttr("3a.bee71b000000", "PIO.A"); err != nil || v != "0" {
+		t.Errorf("lowercase ID: got %q, %v", v, err)
+	}
+	if p, _ := CleanPath("/3a.bee71b000000/PIO.A"); p != "/3A.BEE71B000000/PIO.A" {
+		t.Errorf("CleanPath: got %q", p)
+	}
+
+	lower := newFakeServer(t, map[string]string{
+		"/28.a1b2c3000000/type": "DS18B20",
+	})
+	devs, err := New(lower.addr()).ListDevices()
+	if err != nil || !reflect.DeepEqual(devs, []string{"28.a1b2c3000000"}) {
+		t.Errorf("lowercase listing: got %q, %v", devs, err)
+	}
+}
+
+func TestListDevicesByFamily(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	devs, err := ow.ListDevicesByFamily(0x28)
+	if err != nil || !reflect.DeepEqual(devs, []string{"28.A1B2C3000000"}) {
+		t.Errorf("0x28: got %q, %v", devs, err)
+	}
+	devs, err = ow.ListDevicesByFamily(0x3A, 0x28)
+	if err != nil || len(devs) != 2 {
+		t.Errorf("0x3A, 0x28: got %q, %v", devs, err)
+	}
+	devs, err = ow.ListDevicesByFamily(0x10)
+	if err != nil || len(devs) != 0 {
+		t.Errorf("0x10: got %q, %v", devs, err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	for path, want := range map[string]bool{
+		"/28.A1B2C3000000":             true,
+		"/28.A1B2C3000000/temperature": true,
+		"/28.000000000000":             false,
+		"/28.A1B2C3000000/humidity":    false,
+	} {
+		if got, err := ow.Exists(path); err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", path, got, err, want)
+		}
+	}
+
+	s.close()
+	if _, err := ow.Exists("/28.A1B2C3000000"); !errors.Is(err, ErrConnection) {
+		t.Errorf("closed server: got %v, want connection error", err)
+	}
+}
+
+func TestReadWriteString(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/settings/timeout/volatile": "15\x00",
+	})
+	ow := New(s.addr())
+
+	if v, err := ow.ReadString("/settings/timeout/volatile"); err != nil || v != "15" {
+		t.Errorf("read: got %q, %v", v, err)
+	}
+	if err := ow.WriteString("/settings/timeout/volatile", "30"); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/settings/timeout/volatile"); v != "30" {
+		t.Errorf("written: got %q", v)
+	}
+}
+
+func TestSlash(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	dir, err := ow.DirSlash("/")
+	want := []string{"/28.A1B2C3000000/", "/3A.BEE71B000000/", "/settings/"}
+	if err != nil || !reflect.DeepEqual(dir, want) {
+		t.Errorf("DirSlash: got %q, %v", dir, err)
+	}
+	if data, err := ow.GetSlash("/settings"); err != nil || string(data) != "/settings/units/" {
+		t.Errorf("GetSlash directory: got %q, %v", data, err)
+	}
+	if data, err := ow.GetSlash("/28.A1B2C3000000/type"); err != nil || string(data) != "DS18B20" {
+		t.Errorf("GetSlash file: got %q, %v", data, err)
+	}
+	if _, err := ow.GetSlash("/nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSlash nonexistent: got %v", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	if data, err := ow.Get("/28.A1B2C3000000"); err != nil || string(data) != "/28.A1B2C3000000/temperature,/28.A1B2C3000000/type" {
+		t.Errorf("directory: got %q, %v", data, err)
+	}
+	if data, err := ow.Get("/28.A1B2C3000000/type"); err != nil || string(data) != "DS18B20" {
+		t.Errorf("file: got %q, %v", data, err)
+	}
+}
+
+func TestRawRequest(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr())
+
+	hdr, data, err := ow.RawRequest(MsgRead, "/28.A1B2C3000000/type", nil, 2, 16)
+	if err != nil || string(data) != "18B20" || hdr.Payload != 5 {
+		t.Errorf("read: got %+v, %q, %v", hdr, data, err)
+	}
+	if _, _, err := ow.RawRequest(MsgWrite, attr, []byte("1"), 0, 1); err != nil {
+		t.Errorf("write: %v", err)
+	}
+	if v := s.file(attr); v != "1" {
+		t.Errorf("written: got %q", v)
+	}
+	hdr, _, err = ow.RawRequest(MsgPresence, "/28.000000000000", nil, 0, 0)
+	if !errors.Is(err, ErrNotFound) || hdr.Type != -2 {
+		t.Errorf("presence: got %+v, %v", hdr, err)
+	}
+}
+
+func TestGetAttrBytes(t *testing.T) {
+	page := "\x00\x01\xfe\xff\x00"
+	ow := New(newFakeServer(t, map[string]string{
+		"/26.000000000001/pages/page.0": page,
+	}).addr())
+
+	data, err := ow.GetAttrBytes("26.000000000001", "pages/page.0")
+	if err != nil || string(data) != page {
+		t.Errorf("got %q, %v, want %q", data, err, page)
+	}
+}
+
+func TestReadChunked(t *testing.T) {
+	memory := strings.Repeat("0123456789abcdef", 32)
+	s := newFakeServer(t, map[string]string{
+		"/23.000000000001/memory": memory,
+	})
+	ow := New(s.addr())
+
+	for _, chunk := range []int{32, 100, 1024} {
+		s.mu.Lock()
+		s.requests = nil
+		s.mu.Unlock()
+		data, err := ow.ReadChunked("/23.000000000001/memory", chunk)
+		if err != nil || string(data) != memory {
+			t.Errorf("chunk %d: got %d bytes, %v", chunk, len(data), err)
+		}
+		want := (len(memory) + chunk - 1) / chunk
+		s.mu.Lock()
+		reads := 0
+		for _, hdr := range s.requests {
+			if hdr.Type == MsgRead {
+				reads++
+				if int(hdr.Size) > chunk {
+					t.Errorf("chunk %d: requested %d bytes", chunk, hdr.Size)
+				}
+			}
+		}
+		s.mu.Unlock()
+		if reads != want {
+			t.Errorf("chunk %d: sent %d reads, want %d", chunk, reads, want)
+		}
+	}
+}
+
+func TestReadFull(t *testing.T) {
+	memory := strings.Repeat("0123456789abcdef", 2)
+	ow := New(newFakeServer(t, map[string]string{
+		"/23.000000000001/memory": memory,
+	}).addr())
+
+	for _, tt := range []struct {
+		offset, size int
+		want         string
+	}{
+		{0, 16, memory[:16]},
+		{16, 16, memory[16:]},
+		{24, 16, memory[24:]},
+		{32, 16, ""},
+	} {
+		data, total, err := ow.ReadFull("/23.000000000001/memory", tt.offset, tt.size)
+		if err != nil || string(data) != tt.want || total != len(memory) {
+			t.Errorf("offset %d: got %q, %d, %v", tt.offset, data, total, err)
+		}
+	}
+	if _, _, err := ow.ReadFull("/23.000000000002/memory", 0, 16); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing: got %v", err)
+	}
+}
+
+func TestListDevicesDetailed(t *testing.T) {
+	s := newTestServer(t)
+	s.mu.Lock()
+	s.files["/10.000000000001/temperature"] = "20"
+	s.mu.Unlock()
+	ow := New(s.addr())
+
+	infos, err := ow.ListDevicesDetailed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DeviceInfo{
+		{ID: "10.000000000001", Family: 0x10},
+		{ID: "28.A1B2C3000000", Family: 0x28, Type: "DS18B20"},
+		{ID: "3A.BEE71B000000", Family: 0x3A, Type: "DS2413"},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("got %+v", infos)
+	}
+	for i, info := range infos {
+		err := info.Err
+		info.Err = nil
+		if info != want[i] {
+			t.Errorf("got %+v, want %+v", info, want[i])
+		}
+		if (err != nil) != (i == 0) {
+			t.Errorf("%s: error %v", info.ID, err)
+		}
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+}
+
+func TestBuses(t *testing.T) {
+	ow := New(newFakeServer(t, map[string]string{
+		"/28.A1B2C3000000/type":       "DS18B20",
+		"/28.A1B2C3000001/type":       "DS18B20",
+		"/bus.0/28.A1B2C3000000/type": "DS18B20",
+		"/bus.1/28.A1B2C3000001/type": "DS18B20",
+		"/bus.1/interface/name":       "DS2480B",
+	}).addr())
+
+	buses, err := ow.ListBuses()
+	if err != nil || !reflect.DeepEqual(buses, []string{"bus.0", "bus.1"}) {
+		t.Errorf("ListBuses: got %q, %v", buses, err)
+	}
+	devs, err := ow.DevicesOnBus(1)
+	if err != nil || !reflect.DeepEqual(devs, []string{"28.A1B2C3000001"}) {
+		t.Errorf("DevicesOnBus: got %q, %v", devs, err)
+	}
+	if _, err := ow.DevicesOnBus(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing bus: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestListDevicesContext(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	devs, err := ow.ListDevicesContext(context.Background())
+	want := []string{"28.A1B2C3000000", "3A.BEE71B000000"}
+	if err != nil || !reflect.DeepEqual(devs, want) {
+		t.Errorf("got %q, %v", devs, err)
+	}
+
+	// slow bus delivering listing one item at a time
+	client, server := net.Pipe()
+	s := newTestServer(t)
+	go s.serve(server)
+	slow := New("owserver", WithDialFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return &slowConn{Conn: client}, nil
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	devs, err = slow.ListDevicesContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow: got %v, want deadline exceeded", err)
+	}
+	if !reflect.DeepEqual(devs, want[:1]) {
+		t.Errorf("slow: got %q, want partial %q", devs, want[:1])
+	}
+}
+
+// Connection delaying reads of each response header
+type slowConn struct {
+	net.Conn
+}
+
+func (c *slowConn) Read(b []byte) (int, error) {
+	if len(b) == headerLen {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return c.Conn.Read(b)
 }
@@ -0,0 +1,86 @@
+package ownet
+
+import (
+	"fmt"
+	"path"
+)
+
+// Names of PIO channels of switch device families, indexed by channel:
+// DS2405, DS2406, DS2408, DS2413
+var pioChannels = map[byte][]string{
+	0x05: {""},
+	0x12: {"A", "B"},
+	0x29: {"0", "1", "2", "3", "4", "5", "6", "7"},
+	0x3A: {"A", "B"},
+}
+
+// Get path of attribute attr ("PIO" or "sensed") of channel of the switch
+// device, named according to its family, like "PIO.A" for DS2413.
+func pioPath(device, attr string, channel int) (string, error) {
+	d, err := ParseDeviceID(path.Base(device))
+	if err != nil {
+		return "", err
+	}
+	names, ok := pioChannels[d.Family]
+	if !ok {
+		return "", fmt.Errorf("ownet: device %s has no PIO channels", device)
+	}
+	if channel < 0 || channel >= len(names) {
+		return "", fmt.Errorf("ownet: device %s has no PIO channel %d", device, channel)
+	}
+	if names[channel] != "" {
+		attr += "." + names[channel]
+	}
+	return fmt.Sprintf("/%s/%s", device, attr), nil
+}
+
+// Switch PIO output channel of DS2405, DS2406, DS2408 or DS2413 device on
+// or off. Channels are numbered from 0, e.g. 1 is PIO.B of DS2413.
+// Outputs are open drain and active low: switching on makes the output
+// transistor conduct, pulling the line low, which owserver represents as
+// writing "1" to the PIO attribute.
+// Returns nil on success, error otherwise.
+func (ow *OW) SetPIO(device string, channel int, on bool) error {
+	p, err := pioPath(device, "PIO", channel)
+	if err != nil {
+		return err
+	}
+	v := "0"
+	if on {
+		v = "1"
+	}
+	return ow.WriteString(p, v)
+}
+
+// Get state of PIO output channel of the device, as set by SetPIO: true if
+// the output transistor is on, pulling the line low. Actual line level, which
+// external circuits may drive low as well, is read by ReadSensed.
+// Returns state and error if any.
+func (ow *OW) ReadPIO(device string, channel int) (bool, error) {
+	p, err := pioPath(device, "PIO", channel)
+	if err != nil {
+		return false, err
+	}
+	return ow.ReadBool(p)
+}
+
+// Get level of PIO channel line of the device: true if it is high, i.e. the
+// output is off and nothing else pulls the line low.
+// Returns level and error if any.
+func (ow *OW) ReadSensed(device string, channel int) (bool, error) {
+	p, err := pioPath(device, "sensed", channel)
+	if err != nil {
+		return false, err
+	}
+	return ow.ReadBool(p)
+}
+
+// Switch PIO output channel of the device on or off. See OW.SetPIO.
+func (d *Device) SetPIO(channel int, on bool) error {
+	return d.ow.SetPIO(d.path(), channel, on)
+}
+
+// Get state of PIO output channel of the device. See OW.ReadPIO.
+func (d *Device) ReadPIO(channel int) (bool, error) {
+	return d.ow.ReadPIO(d.path(), channel)
+}
@@ -0,0 +1,39 @@
+package ownet
+
+import "testing"
+
+func TestPIO(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/3A.BEE71B000000/PIO.A":    "0",
+		"/3A.BEE71B000000/PIO.B":    "0",
+		"/3A.BEE71B000000/sensed.B": "1",
+		"/29.000000000001/PIO.7":    "0",
+	})
+	ow := New(s.addr())
+
+	if err := ow.SetPIO("3A.BEE71B000000", 1, true); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/3A.BEE71B000000/PIO.B"); v != "1" {
+		t.Errorf("DS2413 channel 1: got %q, want %q", v, "1")
+	}
+	if on, err := ow.Device("3A.BEE71B000000").ReadPIO(1); err != nil || !on {
+		t.Errorf("ReadPIO: got %v, %v", on, err)
+	}
+	if high, err := ow.ReadSensed("3A.BEE71B000000", 1); err != nil || !high {
+		t.Errorf("ReadSensed: got %v, %v", high, err)
+	}
+	if err := ow.Device("29.000000000001").SetPIO(7, true); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/29.000000000001/PIO.7"); v != "1" {
+		t.Errorf("DS2408 channel 7: got %q, want %q", v, "1")
+	}
+
+	if err := ow.SetPIO("3A.BEE71B000000", 2, true); err == nil {
+		t.Error("DS2413 channel 2: no error")
+	}
+	if _, err := ow.ReadPIO("28.A1B2C3000000", 0); err == nil {
+		t.Error("DS18B20: no error")
+	}
+}
@@ -0,0 +1,79 @@
+package ownet
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Results of reading all paths polled by Poller once
+type Poll struct {
+	Time    time.Time // time reading started
+	Results []Result  // results in order of paths, see ReadBatch
+}
+
+// Poller reads a set of owserver files periodically, e.g. sensors whose
+// values are published by a monitoring service.
+type Poller struct {
+	ow       *OW
+	paths    []string
+	interval time.Duration
+
+	// Maximum random delay of each poll after its tick, so that many
+	// processes started together do not read the bus at the same time.
+	// Default is 0, meaning no delay.
+	Jitter time.Duration
+}
+
+// Create a new Poller reading paths with ow every interval. Non-positive
+// interval is taken as one second.
+func NewPoller(ow *OW, paths []string, interval time.Duration) *Poller {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Poller{ow: ow, paths: paths, interval: interval}
+}
+
+// Start polling in background until ctx is done. Each poll reads all paths
+// over a single connection, see ReadBatch, and is sent to the returned
+// channel, which is closed when polling stops. Polls never overlap: ticks
+// passing while a poll is still in progress or waiting for the receiver are
+// skipped. Errors reading paths are reported in results.
+func (p *Poller) Start(ctx context.Context) <-chan Poll {
+	polls := make(chan Poll)
+	go func() {
+		defer close(polls)
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			if p.Jitter > 0 {
+				select {
+				case <-time.After(time.Duration(rand.Int63n(int64(p.Jitter)))):
+				case <-ctx.Done():
+					return
+				}
+			}
+			poll := Poll{Time: time.Now()}
+			poll.Results, _ = p.ow.readBatch(ctx, p.paths)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case polls <- poll:
+			case <-ctx.Done():
+				return
+			}
+			// drop tick that passed during the poll
+			select {
+			case <-t.C:
+			default:
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return polls
+}
@@ -0,0 +1,56 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	p := NewPoller(ow, []string{"/28.A1B2C3000000/temperature", "/28.000000000000/temperature"}, 5*time.Millisecond)
+	p.Jitter = 2 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := p.Start(ctx)
+	for i := 0; i < 3; i++ {
+		poll := <-polls
+		if len(poll.Results) != 2 {
+			t.Fatalf("got %d results, want 2", len(poll.Results))
+		}
+		if r := poll.Results[0]; r.Err != nil || string(r.Data) != "     21.5" {
+			t.Errorf("present sensor: got %q, %v", r.Data, r.Err)
+		}
+		if r := poll.Results[1]; !errors.Is(r.Err, ErrNotFound) {
+			t.Errorf("missing sensor: got %v, want ErrNotFound", r.Err)
+		}
+	}
+	cancel()
+	for range polls {
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}
+
+func TestPollerNoOverlap(t *testing.T) {
+	s := newTestServer(t)
+	s.delay = 20 * time.Millisecond
+	ow := NewPool(s.addr(), 4)
+	defer ow.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := NewPoller(ow, []string{"/28.A1B2C3000000/type"}, time.Millisecond).Start(ctx)
+	for i := 0; i < 3; i++ {
+		<-polls
+	}
+	cancel()
+	for range polls {
+	}
+	if n := s.maxActiveCount(); n != 1 {
+		t.Errorf("%d polls in progress at once, want 1", n)
+	}
+}
@@ -0,0 +1,93 @@
+package ownet
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool of connections to owserver
+type pool struct {
+	sem  chan struct{} // limits number of connections in use
+	mu   sync.Mutex
+	idle []*conn
+	gen  int // incremented by close, to drop connections in use at that time
+}
+
+// Create a new OWNet client object which maintains a pool of up to maxConns
+// persistent connections to owserver, so that concurrent operations proceed
+// in parallel, each on a connection of its own. Idle connections are reused,
+// new ones are dialed when none is idle, and failed ones are dropped.
+// When maxConns connections are in use, operations wait for one to be
+// released. Options are the same as for New.
+func NewPool(address string, maxConns int, opts ...Option) *OW {
+	if maxConns < 1 {
+		maxConns = 1
+	}
+	ow := New(address, append([]Option{WithPersistent()}, opts...)...)
+	ow.pool = &pool{
+		sem: make(chan struct{}, maxConns),
+	}
+	return ow
+}
+
+// Get idle connection or a new unconnected one, waiting until number of
+// connections in use drops below the limit.
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		return c, nil
+	}
+	return &conn{gen: p.gen}, nil
+}
+
+// Return connection to the pool. Connections that were closed, whether by
+// failure or owserver refusing to keep them open, are dropped, as are those
+// that were in use when the pool was closed.
+func (p *pool) put(c *conn) {
+	p.mu.Lock()
+	if c.gen != p.gen {
+		c.close()
+	}
+	if c.Conn != nil {
+		p.idle = append(p.idle, c)
+	}
+	p.mu.Unlock()
+	<-p.sem
+}
+
+// Take an idle connection without waiting, or nil if there is none.
+func (p *pool) getIdle() *conn {
+	select {
+	case p.sem <- struct{}{}:
+	default:
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		return c
+	}
+	<-p.sem
+	return nil
+}
+
+// Close all idle connections. Connections in use are closed when released.
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.idle {
+		c.close()
+	}
+	p.idle = nil
+	p.gen++
+}
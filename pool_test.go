@@ -0,0 +1,157 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	s.delay = 10 * time.Millisecond
+	const maxConns = 3
+	ow := NewPool(s.addr(), maxConns)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if typ, err := ow.GetType("28.000000000001"); err != nil || typ != "DS18B20" {
+				t.Errorf("GetType: got %q, %v", typ, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := s.dialCount(); n > maxConns {
+		t.Errorf("dialed %d connections, want at most %d", n, maxConns)
+	}
+	if n := s.maxActiveCount(); n < 2 {
+		t.Errorf("at most %d connections were in use simultaneously", n)
+	}
+}
+
+func TestPoolEvict(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	ow := NewPool(s.addr(), 1)
+	ow.SetRetries(0)
+
+	if _, err := ow.GetType("28.000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	s.dropNext(1)
+	if _, err := ow.GetType("28.000000000001"); err == nil {
+		t.Fatal("expected error from dropped connection")
+	}
+	if n := len(ow.pool.idle); n != 0 {
+		t.Errorf("%d idle connections after failure, want 0", n)
+	}
+	if _, err := ow.GetType("28.000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.dialCount(); n != 2 {
+		t.Errorf("dialed %d connections, want 2", n)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/type": "DS18B20",
+	})
+	ow := NewPool(s.addr(), 2)
+
+	c, err := ow.acquire(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.readWhole(t.Context(), "/28.000000000001/type", 0); err != nil {
+		t.Fatal(err)
+	}
+	ow.Close()
+	ow.release(c)
+	if n := len(ow.pool.idle); n != 0 {
+		t.Errorf("%d idle connections after Close, want 0", n)
+	}
+}
+
+func TestPoolCloseContext(t *testing.T) {
+	ow := NewPool(newTestServer(t).addr(), 2)
+
+	c, err := ow.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ow.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("in use: got %v, want deadline exceeded", err)
+	}
+	ow.release(c)
+	if err := ow.CloseContext(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if n := len(ow.pool.sem); n != 0 {
+		t.Errorf("%d connections in use after CloseContext, want 0", n)
+	}
+}
+
+func TestPoolNoGlobalLock(t *testing.T) {
+	ow := NewPool(newTestServer(t).addr(), 2)
+	defer ow.Close()
+
+	// operation in progress on one connection
+	c, err := ow.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ow.release(c)
+
+	done := make(chan error)
+	go func() {
+		ow.SetTemperatureScale(Fahrenheit)
+		_, err := ow.Temperature("28.A1B2C3000000")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("operation blocked by another one in progress")
+	}
+	if c.sg&flagTempScaleMask != 0 {
+		t.Error("settings of operation in progress changed")
+	}
+}
+
+func TestConnectPerRequestNoGlobalLock(t *testing.T) {
+	s := newTestServer(t)
+	s.delay = 50 * time.Millisecond
+	ow := New(s.addr())
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d >= 4*s.delay {
+		t.Errorf("requests took %v, not run in parallel", d)
+	}
+	if n := s.maxActiveCount(); n < 2 {
+		t.Errorf("at most %d connections open at once, want more", n)
+	}
+}
@@ -0,0 +1,47 @@
+package ownet
+
+import (
+	"io"
+)
+
+// Reader of owserver file at a path, see OpenReaderAt
+type readerAt struct {
+	ow   *OW
+	path string
+	size int64
+}
+
+// Get reader of owserver file at path implementing io.ReaderAt, e.g. for use
+// with io.SectionReader, along with file size reported by owserver.
+// Returns reader, size and error if any.
+func (ow *OW) OpenReaderAt(path string) (io.ReaderAt, int64, error) {
+	size, err := ow.Size(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &readerAt{ow: ow, path: path, size: int64(size)}, int64(size), nil
+}
+
+// Read len(p) bytes starting at offset off, issuing as many reads as needed.
+// Reading past the end of file returns the available bytes and io.EOF.
+func (r *readerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if rest := r.size - off; int64(len(p)) > rest {
+		p = p[:rest]
+		err = io.EOF
+	}
+	for n < len(p) {
+		m, rerr := r.ow.Read(r.path, int(off)+n, p[n:])
+		n += m
+		if rerr == io.EOF {
+			// file got shorter than reported
+			return n, io.ErrUnexpectedEOF
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+	return n, err
+}
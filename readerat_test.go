@@ -0,0 +1,55 @@
+package ownet
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderAt(t *testing.T) {
+	page := strings.Repeat("0123456789abcdef", 32)
+	s := newFakeServer(t, map[string]string{
+		"/23.000000000001/memory": page,
+	})
+	ow := New(s.addr())
+
+	r, size, err := ow.OpenReaderAt("/23.000000000001/memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(page)) {
+		t.Errorf("size: got %d, want %d", size, len(page))
+	}
+	data, err := io.ReadAll(io.NewSectionReader(r, 100, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != page[100:300] {
+		t.Errorf("got %q, want %q", data, page[100:300])
+	}
+
+	buf := make([]byte, 32)
+	n, err := r.ReadAt(buf, size-10)
+	if n != 10 || err != io.EOF || string(buf[:n]) != page[len(page)-10:] {
+		t.Errorf("read at end: got %q, %v", buf[:n], err)
+	}
+}
+
+func TestReadEOF(t *testing.T) {
+	ow := New(newFakeServer(t, map[string]string{
+		"/23.000000000001/memory": "0123456789",
+	}).addr())
+
+	buf := make([]byte, 8)
+	if n, err := ow.Read("/23.000000000001/memory", 8, buf); n != 2 || err != nil {
+		t.Errorf("short read: got %d, %v", n, err)
+	}
+	for _, off := range []int{10, 20} {
+		if n, err := ow.Read("/23.000000000001/memory", off, buf); n != 0 || err != io.EOF {
+			t.Errorf("offset %d: got %d, %v, want io.EOF", off, n, err)
+		}
+	}
+	if n, err := ow.Read("/23.000000000001/memory", 10, nil); n != 0 || err != nil {
+		t.Errorf("empty buffer: got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,43 @@
+package ownet
+
+import (
+	"sync"
+)
+
+// Group of reads in flight, keyed by path
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// Read in flight, whose result is shared by all callers waiting for it
+type flight struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// Call fn, unless a call for the same key is already in flight, in which
+// case wait for it and return its result instead. Once a call completes, the
+// next one for the key calls fn again.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.data, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	f.data, f.err = fn()
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f.data, f.err
+}
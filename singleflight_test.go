@@ -0,0 +1,59 @@
+package ownet
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSingleFlight(t *testing.T) {
+	s := newTestServer(t)
+	s.delay = 20 * time.Millisecond
+	ow := NewPool(s.addr(), 10, WithSingleFlight())
+	defer ow.Close()
+
+	reads := func() (n int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, hdr := range s.requests {
+			if hdr.Type == MsgRead {
+				n++
+			}
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if temp, err := ow.Temperature("28.A1B2C3000000"); err != nil || temp != 21.5 {
+				t.Errorf("got %v, %v", temp, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := reads(); n != 1 {
+		t.Errorf("sent %d reads, want 1", n)
+	}
+
+	if _, err := ow.Temperature("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := reads(); n != 2 {
+		t.Errorf("read after completion: sent %d reads, want 2", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ow.Temperature("28.000000000000"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("got %v, want ErrNotFound", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
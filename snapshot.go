@@ -0,0 +1,83 @@
+package ownet
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// Values of device attributes at a point in time, see Snapshot. Snapshots
+// marshal to JSON, e.g. for archiving and comparing later.
+type BusSnapshot struct {
+	Time    time.Time                    `json:"time"`
+	Devices map[string]map[string]string `json:"devices"`          // values by device ID and attribute path relative to device
+	Errors  map[string]string            `json:"errors,omitempty"` // read errors by attribute path
+}
+
+// Attributes skipped by Snapshot, by prefix of their names: measurements,
+// which are volatile and slow to read, and memory contents, which are large
+var volatileAttrs = []string{
+	"temperature", "fasttemp", "latesttemp", "humidity", "pressure",
+	"VAD", "VDD", "vis", "sensed", "latch", "counter", "memory", "pages",
+}
+
+// Report whether attribute at path is not volatile, see volatileAttrs.
+func stableAttr(p string) bool {
+	name := path.Base(p)
+	for _, prefix := range volatileAttrs {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Take snapshot of stable attributes of all devices on the bus, like
+// configuration and identification, skipping measurements and memory
+// contents. See SnapshotFilter.
+func (ow *OW) Snapshot() (BusSnapshot, error) {
+	return ow.SnapshotFilter(stableAttr)
+}
+
+// Take snapshot of attributes of all devices on the bus for which keep
+// returns true, given full attribute path. Devices are walked with Walk,
+// including those on branches of DS2409 couplers. Failure to read an
+// attribute or list a directory does not abort the snapshot, but is recorded
+// in its Errors.
+// Returns snapshot and error if devices could not be listed.
+func (ow *OW) SnapshotFilter(keep func(path string) bool) (BusSnapshot, error) {
+	snap := BusSnapshot{
+		Time:    time.Now(),
+		Devices: make(map[string]map[string]string),
+		Errors:  make(map[string]string),
+	}
+	devs, err := ow.ListDevices()
+	if err != nil {
+		return snap, err
+	}
+	for _, dev := range devs {
+		attrs := make(map[string]string)
+		snap.Devices[dev] = attrs
+		root := "/" + dev
+		err := ow.Walk(root, func(p string, isDir bool, err error) error {
+			if err != nil {
+				snap.Errors[p] = err.Error()
+				return nil
+			}
+			if isDir || !keep(p) {
+				return nil
+			}
+			v, err := ow.ReadString(p)
+			if err != nil {
+				snap.Errors[p] = err.Error()
+				return nil
+			}
+			attrs[strings.TrimPrefix(p, root+"/")] = strings.TrimSpace(v)
+			return nil
+		})
+		if err != nil {
+			snap.Errors[root] = err.Error()
+		}
+	}
+	return snap, nil
+}
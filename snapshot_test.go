@@ -0,0 +1,50 @@
+package ownet
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.A1B2C3000000/type":        "DS18B20",
+		"/28.A1B2C3000000/temperature": "     21.5",
+		"/28.A1B2C3000000/temphigh":    "     75",
+		"/28.A1B2C3000000/errata/trim": "1",
+		"/3A.BEE71B000000/type":        "DS2413",
+		"/3A.BEE71B000000/sensed.A":    "1",
+		"/3A.BEE71B000000/PIO.A":       "0",
+		"/settings/timeout/volatile":   "15",
+	})
+	s.fail = map[string]OWErr{"/3A.BEE71B000000/PIO.A": errAccess}
+	ow := New(s.addr())
+
+	snap, err := ow.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"28.A1B2C3000000": {"type": "DS18B20", "temphigh": "75", "errata/trim": "1"},
+		"3A.BEE71B000000": {"type": "DS2413"},
+	}
+	if !reflect.DeepEqual(snap.Devices, want) {
+		t.Errorf("got %v, want %v", snap.Devices, want)
+	}
+	if _, ok := snap.Errors["/3A.BEE71B000000/PIO.A"]; !ok || len(snap.Errors) != 1 {
+		t.Errorf("errors: got %v", snap.Errors)
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back BusSnapshot
+	if err := json.Unmarshal(data, &back); err != nil || !reflect.DeepEqual(back.Devices, snap.Devices) {
+		t.Errorf("JSON round trip: got %v, %v", back.Devices, err)
+	}
+
+	snap, err = ow.SnapshotFilter(func(path string) bool { return path == "/28.A1B2C3000000/temperature" })
+	if err != nil || snap.Devices["28.A1B2C3000000"]["temperature"] != "21.5" || len(snap.Devices["3A.BEE71B000000"]) != 0 {
+		t.Errorf("filter: got %v, %v", snap.Devices, err)
+	}
+}
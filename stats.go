@@ -0,0 +1,49 @@
+package ownet
+
+import (
+	"sync/atomic"
+)
+
+// Traffic counters of OW
+type Stats struct {
+	BytesSent     uint64 // bytes of requests written to owserver
+	BytesReceived uint64 // bytes of responses read from owserver
+	Requests      uint64 // requests sent
+	Errors        uint64 // requests that failed or owserver responded with error to
+	Dials         uint64 // connections dialed
+	Reuses        uint64 // requests sent over connection kept open in persistent mode
+}
+
+// Counters updated by connections, safe for concurrent use
+type stats struct {
+	sent, received, requests, errors atomic.Uint64
+	dials, reuses                    atomic.Uint64
+}
+
+// Get snapshot of traffic counters accumulated since OW was created or
+// counters were last reset. In persistent mode, Dials growing along with
+// Reuses means idle connections get dropped, e.g. by a firewall, and
+// keepalive pings should be sent more often, see StartKeepalive.
+func (ow *OW) Stats() Stats {
+	return Stats{
+		BytesSent:     ow.stats.sent.Load(),
+		BytesReceived: ow.stats.received.Load(),
+		Requests:      ow.stats.requests.Load(),
+		Errors:        ow.stats.errors.Load(),
+		Dials:         ow.stats.dials.Load(),
+		Reuses:        ow.stats.reuses.Load(),
+	}
+}
+
+// Reset traffic counters to zero, e.g. for reporting per interval.
+// Returns values of counters before reset.
+func (ow *OW) ResetStats() Stats {
+	return Stats{
+		BytesSent:     ow.stats.sent.Swap(0),
+		BytesReceived: ow.stats.received.Swap(0),
+		Requests:      ow.stats.requests.Swap(0),
+		Errors:        ow.stats.errors.Swap(0),
+		Dials:         ow.stats.dials.Swap(0),
+		Reuses:        ow.stats.reuses.Swap(0),
+	}
+}
@@ -0,0 +1,54 @@
+package ownet
+
+import (
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	ow := New(newTestServer(t).addr())
+
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	ow.GetType("28.000000000000")
+
+	// "/28.A1B2C3000000/type\0" and "/28.000000000000/type\0" after headers,
+	// "DS18B20" in response to the first one
+	want := Stats{
+		BytesSent:     2 * (headerLen + 22),
+		BytesReceived: 2*headerLen + 7,
+		Requests:      2,
+		Errors:        1,
+		Dials:         2,
+	}
+	if st := ow.Stats(); st != want {
+		t.Errorf("got %+v, want %+v", st, want)
+	}
+	if st := ow.ResetStats(); st != want {
+		t.Errorf("reset: got %+v, want %+v", st, want)
+	}
+	if st := ow.Stats(); st != (Stats{}) {
+		t.Errorf("after reset: got %+v", st)
+	}
+}
+
+func TestStatsDials(t *testing.T) {
+	s := newTestServer(t)
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.closeConns()
+	if _, err := ow.GetType("28.A1B2C3000000"); err != nil {
+		t.Fatal(err)
+	}
+	// the request over the connection closed by owserver is retried on a
+	// new one
+	if st := ow.Stats(); st.Dials != 2 || st.Reuses != 3 {
+		t.Errorf("got %d dials, %d reuses, want 2, 3", st.Dials, st.Reuses)
+	}
+}
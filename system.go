@@ -0,0 +1,114 @@
+package ownet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Get version of owserver, as reported in /system/process/version.
+// Returns version string and error if any.
+func (ow *OW) ServerVersion() (string, error) {
+	v, err := ow.ReadString("/system/process/version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// Get all owserver settings from /settings tree.
+// Returns values keyed by path relative to /settings, like
+// "units/temperature_scale", and error if any. Settings that could not be
+// read are missing from the result, and listed in error.
+func (ow *OW) Settings() (map[string]string, error) {
+	return ow.readTree("/settings", "/settings")
+}
+
+// Get owserver statistics from /statistics tree, like numbers of bus resets
+// or CRC errors, limited to subtree at subpath, like "errors", if not empty.
+// Returns values keyed by path relative to /statistics, like
+// "errors/CRC8_errors", and error if any. Values that could not be read are
+// missing from the result, and listed in error.
+func (ow *OW) Statistics(subpath string) (map[string]string, error) {
+	return ow.readTree(path.Join("/statistics", subpath), "/statistics")
+}
+
+// Read values of all files in tree at root, keyed by path relative to base,
+// with whitespace trimmed.
+func (ow *OW) readTree(root, base string) (map[string]string, error) {
+	values := make(map[string]string)
+	var errs []error
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		items, err := ow.dir(context.Background(), dir, MsgDirAllSlash)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if strings.HasSuffix(item, "/") {
+				if err := walk(strings.TrimSuffix(item, "/")); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			v, err := ow.ReadString(item)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			values[strings.TrimPrefix(item, base+"/")] = strings.TrimSpace(v)
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return values, errors.Join(errs...)
+}
+
+// Kind of owserver cache timeout, named as in /settings/timeout
+type CacheTimeout string
+
+const (
+	CacheVolatile  CacheTimeout = "volatile"  // changing values, like temperature
+	CacheStable    CacheTimeout = "stable"    // values that rarely change, like memory
+	CacheDirectory CacheTimeout = "directory" // directory listings
+	CachePresence  CacheTimeout = "presence"  // device presence
+)
+
+// Get path of setting of cache timeout of kind, checking that kind is known.
+func cacheTimeoutPath(kind CacheTimeout) (string, error) {
+	switch kind {
+	case CacheVolatile, CacheStable, CacheDirectory, CachePresence:
+		return "/settings/timeout/" + string(kind), nil
+	}
+	return "", fmt.Errorf("ownet: unknown cache timeout %q", string(kind))
+}
+
+// Get owserver cache timeout of kind, from /settings/timeout.
+// Returns timeout and error if any.
+func (ow *OW) GetCacheTimeout(kind CacheTimeout) (time.Duration, error) {
+	p, err := cacheTimeoutPath(kind)
+	if err != nil {
+		return 0, err
+	}
+	s, err := ow.ReadInt(p)
+	return time.Duration(s) * time.Second, err
+}
+
+// Set owserver cache timeout of kind, in /settings/timeout, to d truncated to
+// whole seconds. It applies to all clients of owserver until it restarts.
+// Returns nil on success, otherwise error.
+func (ow *OW) SetCacheTimeout(kind CacheTimeout, d time.Duration) error {
+	p, err := cacheTimeoutPath(kind)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("ownet: invalid cache timeout %v", d)
+	}
+	return ow.WriteInt(p, int64(d/time.Second))
+}
@@ -0,0 +1,76 @@
+package ownet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServerVersion(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/system/process/version":           "3.2p4 \n\x00",
+		"/settings/units/temperature_scale": "C",
+		"/settings/units/pressure_scale":    "mbar",
+		"/settings/timeout/volatile":        "15",
+	})
+	ow := New(s.addr())
+
+	v, err := ow.ServerVersion()
+	if err != nil || v != "3.2p4" {
+		t.Errorf("version: got %q, %v", v, err)
+	}
+
+	settings, err := ow.Settings()
+	want := map[string]string{
+		"units/temperature_scale": "C",
+		"units/pressure_scale":    "mbar",
+		"timeout/volatile":        "15",
+	}
+	if err != nil || !reflect.DeepEqual(settings, want) {
+		t.Errorf("settings: got %v, %v", settings, err)
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/statistics/errors/CRC8_errors":   "          3",
+		"/statistics/errors/CRC16_errors":  "          0",
+		"/statistics/cache/dir_hits":       "         12",
+		"/statistics/bus/bus.0/bus_resets": "         40",
+	})
+	ow := New(s.addr())
+
+	all, err := ow.Statistics("")
+	if err != nil || len(all) != 4 || all["bus/bus.0/bus_resets"] != "40" {
+		t.Errorf("all: got %v, %v", all, err)
+	}
+	errs, err := ow.Statistics("errors")
+	want := map[string]string{"errors/CRC8_errors": "3", "errors/CRC16_errors": "0"}
+	if err != nil || !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors: got %v, %v", errs, err)
+	}
+}
+
+func TestCacheTimeout(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/settings/timeout/volatile":  "     15",
+		"/settings/timeout/directory": "     60",
+	})
+	ow := New(s.addr())
+
+	if d, err := ow.GetCacheTimeout(CacheVolatile); err != nil || d != 15*time.Second {
+		t.Errorf("volatile: got %v, %v", d, err)
+	}
+	if err := ow.SetCacheTimeout(CacheDirectory, 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/settings/timeout/directory"); v != "120" {
+		t.Errorf("set directory: got %q, want %q", v, "120")
+	}
+	if _, err := ow.GetCacheTimeout("bogus"); err == nil {
+		t.Error("unknown kind: no error")
+	}
+	if err := ow.SetCacheTimeout(CacheStable, -time.Second); err == nil {
+		t.Error("negative timeout: no error")
+	}
+}
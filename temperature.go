@@ -0,0 +1,206 @@
+package ownet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Conversion time of DS18B20 at 12-bit resolution, the longest one
+const maxConversionTime = 750 * time.Millisecond
+
+// Get conversion time of DS18B20 at resolution of bits, halving with each bit
+// less, down to 94ms at 9 bits.
+func conversionTime(bits int) time.Duration {
+	if bits < 9 || bits > 12 {
+		return maxConversionTime
+	}
+	return maxConversionTime >> (12 - bits)
+}
+
+// Families of temperature sensors supporting simultaneous conversion:
+// DS18S20, DS1822, DS18B20, DS1825, DS28EA00
+var simultaneousFamilies = []byte{0x10, 0x22, 0x28, 0x3B, 0x42}
+
+// Start temperature conversion on all sensors on the bus at once. After the
+// conversion time passes, converted values can be read quickly from
+// "latesttemp" attribute of each sensor.
+func (ow *OW) SimultaneousTemperature() error {
+	return ow.Write("/simultaneous/temperature", 0, []byte("1"))
+}
+
+// Get time simultaneous conversion of sensors devs takes, that of the slowest
+// one according to their resolutions, which are read over a single
+// connection. Sensors of fixed or unknown resolution take the longest.
+func (ow *OW) conversionWait(devs []string) time.Duration {
+	var paths []string
+	for _, dev := range devs {
+		if family, err := resolutionFamily(dev); err != nil || family == 0x10 {
+			return maxConversionTime
+		}
+		paths = append(paths, fmt.Sprintf("/%s/resolution", dev))
+	}
+	res, err := ow.ReadBatch(paths)
+	if err != nil {
+		return maxConversionTime
+	}
+	var wait time.Duration
+	for _, r := range res {
+		bits, err := parseFloat(r.Path, r.Data)
+		if r.Err != nil || err != nil {
+			return maxConversionTime
+		}
+		if d := conversionTime(int(bits)); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// Read temperatures of all DS18B20-like sensors on the bus, converted
+// simultaneously with SimultaneousTemperature. Conversion is waited for as
+// long as the sensor of highest resolution needs, see Resolution.
+// Parasitically powered sensors need no longer wait, as owserver holds the
+// strong pullup of the data line for the whole conversion.
+// Returns temperatures keyed by device identifier, and error if any. Sensors
+// that could not be read are missing from the result, and listed in error.
+func (ow *OW) ReadAllTemperatures() (map[string]float64, error) {
+	devs, err := ow.ListDevicesByFamily(simultaneousFamilies...)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, dev := range devs {
+		paths = append(paths, fmt.Sprintf("/%s/latesttemp", dev))
+	}
+	if len(paths) == 0 {
+		return map[string]float64{}, nil
+	}
+	wait := ow.conversionWait(devs)
+	if err := ow.SimultaneousTemperature(); err != nil {
+		return nil, err
+	}
+	time.Sleep(wait)
+
+	res, err := ow.ReadBatch(paths)
+	if err != nil {
+		return nil, err
+	}
+	temps := make(map[string]float64)
+	var errs []error
+	for _, r := range res {
+		dev := strings.Split(r.Path, "/")[1]
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		t, err := parseFloat(r.Path, r.Data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		temps[dev] = t
+	}
+	return temps, errors.Join(errs...)
+}
+
+// Power supply mode of a device
+type PowerMode int
+
+const (
+	PowerParasitic PowerMode = iota // powered from the data line
+	PowerExternal                   // powered from a dedicated supply line
+)
+
+func (m PowerMode) String() string {
+	switch m {
+	case PowerParasitic:
+		return "parasitic"
+	case PowerExternal:
+		return "external"
+	}
+	return "unknown"
+}
+
+// Get power supply mode of the device from its "power" attribute, e.g. to
+// tell whether DS18B20 sensors converting simultaneously need strong pullup
+// of the data line.
+// Returns power mode and error if any.
+func (ow *OW) PowerMode(device string) (PowerMode, error) {
+	external, err := ow.ReadBool(fmt.Sprintf("/%s/power", device))
+	if err != nil {
+		return PowerParasitic, err
+	}
+	if external {
+		return PowerExternal, nil
+	}
+	return PowerParasitic, nil
+}
+
+// Get power supply mode of the device. See OW.PowerMode.
+func (d *Device) PowerMode() (PowerMode, error) {
+	return d.ow.PowerMode(d.path())
+}
+
+// Families of temperature sensors with resolution configurable from 9 to 12
+// bits: DS1822, DS18B20, DS1825, DS28EA00
+var resolutionFamilies = []byte{0x22, 0x28, 0x3B, 0x42}
+
+// Fixed resolution of DS18S20
+const ds18s20Resolution = 9
+
+// Get family of the temperature sensor, checking that its resolution is
+// configurable.
+func resolutionFamily(device string) (byte, error) {
+	id, err := ParseDeviceID(path.Base(device))
+	if err != nil {
+		return 0, err
+	}
+	if id.Family != 0x10 && bytes.IndexByte(resolutionFamilies, id.Family) < 0 {
+		return 0, fmt.Errorf("ownet: device %s does not support configurable resolution", device)
+	}
+	return id.Family, nil
+}
+
+// Get resolution of the temperature sensor in bits, from its "resolution"
+// attribute. DS18S20 resolution is fixed to 9 bits, which is returned without
+// a request.
+// Returns resolution and error if any.
+func (ow *OW) Resolution(device string) (int, error) {
+	family, err := resolutionFamily(device)
+	if err != nil {
+		return 0, err
+	}
+	if family == 0x10 {
+		return ds18s20Resolution, nil
+	}
+	bits, err := ow.ReadInt(fmt.Sprintf("/%s/resolution", device))
+	return int(bits), err
+}
+
+// Set resolution of the temperature sensor to bits, from 9 to 12. Lower
+// resolution shortens conversion time, from 750ms at 12 bits to 94ms at 9.
+// Returns nil on success, otherwise error.
+func (ow *OW) SetResolution(device string, bits int) error {
+	family, err := resolutionFamily(device)
+	if err != nil {
+		return err
+	}
+	if family == 0x10 {
+		return fmt.Errorf("ownet: device %s does not support configurable resolution", device)
+	}
+	if bits < 9 || bits > 12 {
+		return fmt.Errorf("ownet: invalid resolution %d bits, must be 9 to 12", bits)
+	}
+	return ow.WriteInt(fmt.Sprintf("/%s/resolution", device), int64(bits))
+}
+
+// Get temperature of the sensor converted at 9-bit resolution, which is
+// quicker than at the configured one, from its "fasttemp" attribute.
+// Returns temperature and error if any.
+func (ow *OW) FastTemperature(device string) (float64, error) {
+	return ow.ReadFloat(fmt.Sprintf("/%s/fasttemp", device))
+}
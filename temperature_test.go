@@ -0,0 +1,102 @@
+package ownet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadAllTemperatures(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/simultaneous/temperature":   "0",
+		"/28.A1B2C3000000/latesttemp": "     21.5",
+		"/28.A1B2C4000000/latesttemp": "    -3.25",
+		"/3A.BEE71B000000/PIO.A":      "0",
+	})
+	ow := New(s.addr())
+
+	temps, err := ow.ReadAllTemperatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"28.A1B2C3000000": 21.5, "28.A1B2C4000000": -3.25}
+	if !reflect.DeepEqual(temps, want) {
+		t.Errorf("got %v, want %v", temps, want)
+	}
+	if v := s.file("/simultaneous/temperature"); v != "1" {
+		t.Errorf("conversion not triggered: %q", v)
+	}
+}
+
+func TestConversionWait(t *testing.T) {
+	ow := New(newFakeServer(t, map[string]string{
+		"/28.000000000001/resolution": "           9",
+		"/28.000000000002/resolution": "          10",
+		"/22.000000000003/resolution": "          11",
+	}).addr())
+
+	for _, tt := range []struct {
+		devs []string
+		want time.Duration
+	}{
+		{[]string{"28.000000000001"}, 93750 * time.Microsecond},
+		{[]string{"28.000000000001", "28.000000000002"}, 187500 * time.Microsecond},
+		{[]string{"28.000000000001", "22.000000000003"}, 375 * time.Millisecond},
+		{[]string{"28.000000000001", "10.000000000004"}, 750 * time.Millisecond},
+		{[]string{"28.000000000001", "28.000000000005"}, 750 * time.Millisecond},
+	} {
+		if got := ow.conversionWait(tt.devs); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.devs, got, tt.want)
+		}
+	}
+}
+
+func TestPowerMode(t *testing.T) {
+	ow := New(newFakeServer(t, map[string]string{
+		"/28.000000000001/power": "1",
+		"/28.000000000002/power": "           0",
+	}).addr())
+
+	if m, err := ow.PowerMode("28.000000000001"); err != nil || m != PowerExternal {
+		t.Errorf("external: got %v, %v", m, err)
+	}
+	if m, err := ow.Device("28.000000000002").PowerMode(); err != nil || m != PowerParasitic {
+		t.Errorf("parasitic: got %v, %v", m, err)
+	}
+	if _, err := ow.PowerMode("28.000000000003"); err == nil {
+		t.Error("missing device: no error")
+	}
+}
+
+func TestResolution(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.000000000001/resolution": "          12",
+		"/28.000000000001/fasttemp":   "        21.5",
+	})
+	ow := New(s.addr())
+
+	if bits, err := ow.Resolution("28.000000000001"); err != nil || bits != 12 {
+		t.Errorf("got %d, %v", bits, err)
+	}
+	if err := ow.SetResolution("28.000000000001", 9); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/28.000000000001/resolution"); v != "9" {
+		t.Errorf("set: got %q, want %q", v, "9")
+	}
+	if v, err := ow.FastTemperature("28.000000000001"); err != nil || v != 21.5 {
+		t.Errorf("fasttemp: got %v, %v", v, err)
+	}
+	if err := ow.SetResolution("28.000000000001", 13); err == nil {
+		t.Error("13 bits: no error")
+	}
+	if bits, err := ow.Resolution("10.000000000001"); err != nil || bits != 9 {
+		t.Errorf("DS18S20: got %d, %v", bits, err)
+	}
+	if err := ow.SetResolution("10.000000000001", 9); err == nil {
+		t.Error("DS18S20: no error")
+	}
+	if _, err := ow.Resolution("3A.BEE71B000000"); err == nil {
+		t.Error("DS2413: no error")
+	}
+}
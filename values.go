@@ -0,0 +1,89 @@
+package ownet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Read value of owserver file at path and parse it with parse, trimming the
+// whitespace owserver pads numeric values with.
+func (ow *OW) readValue(path string, parse func(string) error) error {
+	v, err := ow.ReadString(path)
+	if err != nil {
+		return err
+	}
+	if err = parse(strings.TrimSpace(v)); err != nil {
+		return opError("read", path, fmt.Errorf("ownet: invalid value: %w", err))
+	}
+	return nil
+}
+
+// Parse floating point value v read from owserver file at path.
+func parseFloat(path string, v []byte) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(string(v), "\x00")), 64)
+	if err != nil {
+		return 0, opError("read", path, fmt.Errorf("ownet: invalid value: %w", err))
+	}
+	return f, nil
+}
+
+// Read floating point value of owserver file at path.
+// Returns value and error if any.
+func (ow *OW) ReadFloat(path string) (v float64, err error) {
+	err = ow.readValue(path, func(s string) (err error) {
+		v, err = strconv.ParseFloat(s, 64)
+		return
+	})
+	return
+}
+
+// Read integer value of owserver file at path.
+// Returns value and error if any.
+func (ow *OW) ReadInt(path string) (v int64, err error) {
+	err = ow.readValue(path, func(s string) (err error) {
+		v, err = strconv.ParseInt(s, 10, 64)
+		return
+	})
+	return
+}
+
+// Read boolean value of owserver file at path. owserver represents booleans
+// (e.g. PIO states) as "1" and "0".
+// Returns value and error if any.
+func (ow *OW) ReadBool(path string) (v bool, err error) {
+	err = ow.readValue(path, func(s string) error {
+		switch s {
+		case "1":
+			v = true
+		case "0":
+			v = false
+		default:
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		return nil
+	})
+	return
+}
+
+// Write floating point value v to owserver file at path.
+// Returns nil on success, error otherwise.
+func (ow *OW) WriteFloat(path string, v float64) error {
+	return ow.Write(path, 0, []byte(strconv.FormatFloat(v, 'g', -1, 64)))
+}
+
+// Write integer value v to owserver file at path.
+// Returns nil on success, error otherwise.
+func (ow *OW) WriteInt(path string, v int64) error {
+	return ow.Write(path, 0, []byte(strconv.FormatInt(v, 10)))
+}
+
+// Write boolean value v to owserver file at path as "1" or "0".
+// Returns nil on success, error otherwise.
+func (ow *OW) WriteBool(path string, v bool) error {
+	s := "0"
+	if v {
+		s = "1"
+	}
+	return ow.Write(path, 0, []byte(s))
+}
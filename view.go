@@ -0,0 +1,84 @@
+package ownet
+
+// View of owserver namespace under a virtual root. owserver exposes the bus
+// under several prefixes changing how it is accessed, among others:
+//
+//	/uncached      values read from devices, bypassing owserver cache
+//	/alarm         devices in alarm state
+//	/simultaneous  commands to all devices at once
+//	/text          values without padding
+//	/json          values in JSON format
+//
+// Paths given to methods of View are relative to its prefix.
+type View struct {
+	ow     *OW
+	prefix string
+}
+
+// Get view of owserver namespace under prefix, like "/uncached", accessed
+// via ow.
+func (ow *OW) WithPrefix(prefix string) *View {
+	return &View{ow: ow, prefix: prefix}
+}
+
+// Get view of owserver namespace bypassing owserver cache, same as
+// WithPrefix("/uncached").
+func (ow *OW) Uncached() *View {
+	return ow.WithPrefix("/uncached")
+}
+
+// Get prefix of the view.
+func (v *View) Prefix() string {
+	return v.prefix
+}
+
+func (v *View) path(path string) string {
+	return v.prefix + "/" + path
+}
+
+// Same as OW.Dir, but for path under the view prefix. Returned items are
+// full owserver paths.
+func (v *View) Dir(path string) ([]string, error) {
+	return v.ow.Dir(v.path(path))
+}
+
+// Same as OW.Read, but for path under the view prefix.
+func (v *View) Read(path string, offset int, data []byte) (int, error) {
+	return v.ow.Read(v.path(path), offset, data)
+}
+
+// Same as OW.Write, but for path under the view prefix.
+func (v *View) Write(path string, offset int, data []byte) error {
+	return v.ow.Write(v.path(path), offset, data)
+}
+
+// Same as OW.ReadAll, but for path under the view prefix.
+func (v *View) ReadAll(path string) ([]byte, error) {
+	return v.ow.ReadAll(v.path(path))
+}
+
+// Same as OW.ReadString, but for path under the view prefix.
+func (v *View) ReadString(path string) (string, error) {
+	return v.ow.ReadString(v.path(path))
+}
+
+// Same as OW.WriteString, but for path under the view prefix.
+func (v *View) WriteString(path, value string) error {
+	return v.ow.WriteString(v.path(path), value)
+}
+
+// Same as OW.Exists, but for path under the view prefix.
+func (v *View) Exists(path string) (bool, error) {
+	return v.ow.Exists(v.path(path))
+}
+
+// Same as OW.ListDevices, but for devices listed under the view prefix.
+func (v *View) ListDevices() ([]string, error) {
+	return v.ow.listDevices(v.path(""))
+}
+
+// Get device with identifier id accessed under the view prefix. Device
+// presence is not checked.
+func (v *View) Device(id string) *Device {
+	return &Device{ow: v.ow, prefix: v.prefix, id: id}
+}
@@ -0,0 +1,37 @@
+package ownet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestView(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/28.A1B2C3000000/temperature":          "21.5",
+		"/uncached/28.A1B2C3000000/temperature": "22",
+		"/alarm/28.A1B2C3000000/temperature":    "22",
+	})
+	ow := New(s.addr())
+
+	u := ow.Uncached()
+	if v, err := u.ReadString("28.A1B2C3000000/temperature"); err != nil || v != "22" {
+		t.Errorf("ReadString: got %q, %v", v, err)
+	}
+	if temp, err := u.Device("28.A1B2C3000000").Temperature(); err != nil || temp != 22 {
+		t.Errorf("Device.Temperature: got %v, %v", temp, err)
+	}
+	if temp, err := ow.Device("28.A1B2C3000000").Temperature(); err != nil || temp != 21.5 {
+		t.Errorf("cached Device.Temperature: got %v, %v", temp, err)
+	}
+	if err := u.WriteString("28.A1B2C3000000/temperature", "23"); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.file("/uncached/28.A1B2C3000000/temperature"); v != "23" {
+		t.Errorf("written: got %q", v)
+	}
+
+	devs, err := ow.WithPrefix("/alarm").ListDevices()
+	if err != nil || !reflect.DeepEqual(devs, []string{"28.A1B2C3000000"}) {
+		t.Errorf("alarm ListDevices: got %q, %v", devs, err)
+	}
+}
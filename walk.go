@@ -0,0 +1,68 @@
+package ownet
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+)
+
+// Function called by Walk for each visited path, with isDir telling whether
+// it is a directory. Listing a directory that failed is reported by a second
+// call for it with err set. Returning fs.SkipDir for a directory skips its
+// contents, for a file the rest of its directory; returning fs.SkipAll skips
+// everything left. Any other error stops the walk and is returned by it.
+type WalkFunc func(path string, isDir bool, err error) error
+
+// Walk owserver tree at root, calling fn for root and everything below it,
+// directories before their contents, in order of listings. Directories are
+// listed over a single connection of the walk's own, so that fn may use ow
+// in any mode. Branches of DS2409 couplers are walked as their "main" and
+// "aux" subdirectories. Note that root directory of owserver also lists other
+// views of the same devices, like "uncached" and "bus.0", which fn may skip.
+// Returns error returned by fn, if any.
+func (ow *OW) Walk(root string, fn WalkFunc) error {
+	clean, err := CleanPath(root)
+	if err != nil {
+		return fn(root, false, err)
+	}
+	ow.Lock()
+	c := &conn{ow: ow, settings: ow.settings, hold: true}
+	ow.Unlock()
+	defer c.close()
+
+	err = c.walk(context.Background(), clean, fn)
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Walk directory at path, see OW.Walk.
+func (c *conn) walk(ctx context.Context, path string, fn WalkFunc) error {
+	if err := fn(path, true, nil); err != nil {
+		return err
+	}
+	items, err := c.dir(ctx, path, MsgDirAllSlash)
+	if err != nil {
+		if err = fn(path, true, err); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if dir := strings.TrimSuffix(item, "/"); dir != item {
+			err = c.walk(ctx, dir, fn)
+			if err == fs.SkipDir {
+				err = nil
+			}
+		} else {
+			err = fn(item, false, nil)
+		}
+		if err == fs.SkipDir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
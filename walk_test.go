@@ -0,0 +1,72 @@
+package ownet
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/1F.000000000001/type":                      "DS2409",
+		"/1F.000000000001/main/28.A1B2C3000000/":     "",
+		"/1F.000000000001/aux/3A.BEE71B000000/PIO.A": "0",
+		"/settings/units/temperature_scale":          "C",
+	})
+	s.fail = map[string]OWErr{"/1F.000000000001/main/28.A1B2C3000000": errNoDevice}
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	var visited []string
+	err := ow.Walk("/", func(path string, isDir bool, err error) error {
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: %v", path, err)
+			}
+			visited = append(visited, "! "+path)
+			return nil
+		}
+		if isDir {
+			visited = append(visited, "d "+path)
+		} else {
+			visited = append(visited, "f "+path)
+		}
+		if path == "/settings" {
+			return fs.SkipDir
+		}
+		// fn may use ow while walking
+		if _, err := ow.Size("/settings/units/temperature_scale"); err != nil {
+			t.Error(err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"d /",
+		"d /1F.000000000001",
+		"d /1F.000000000001/aux",
+		"d /1F.000000000001/aux/3A.BEE71B000000",
+		"f /1F.000000000001/aux/3A.BEE71B000000/PIO.A",
+		"d /1F.000000000001/main",
+		"d /1F.000000000001/main/28.A1B2C3000000",
+		"! /1F.000000000001/main/28.A1B2C3000000",
+		"f /1F.000000000001/type",
+		"d /settings",
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("got %q, want %q", visited, want)
+	}
+
+	visited = nil
+	stop := errors.New("stop")
+	err = ow.Walk("/1F.000000000001", func(path string, isDir bool, err error) error {
+		visited = append(visited, path)
+		return stop
+	})
+	if err != stop || len(visited) != 1 {
+		t.Errorf("stop: got %v after %q", err, visited)
+	}
+}
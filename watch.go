@@ -0,0 +1,82 @@
+package ownet
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Interval used in place of a non-positive one, which time.NewTicker rejects
+const defaultInterval = time.Second
+
+// Change of watched owserver file value, or failure to read it
+type WatchEvent struct {
+	Path     string
+	Value    string    // new value
+	Previous string    // value before the change
+	Time     time.Time // time value was read
+	Err      error     // error reading value, Value and Previous are not set
+}
+
+// Watch owserver file at path for changes by reading it every interval.
+// The first value read is taken as initial and is not reported; after that,
+// an event is sent to the returned channel whenever the value differs from
+// the last one read, and whenever reading fails.
+// Returns channel of events, and function stopping the watch, which closes
+// the channel.
+func (ow *OW) Watch(path string, interval time.Duration) (<-chan WatchEvent, func()) {
+	return ow.WatchAll([]string{path}, interval)
+}
+
+// Watch owserver files at paths for changes like Watch, but with a single
+// poller reading all of them every interval over a single connection, see
+// ReadBatch. Events of each path are tagged with it, and are sent in order
+// of paths. Non-positive interval is taken as one second.
+func (ow *OW) WatchAll(paths []string, interval time.Duration) (<-chan WatchEvent, func()) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan WatchEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(events)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		prev := make(map[string]string) // last values read, by path
+		for {
+			res, _ := ow.readBatch(ctx, paths)
+			if ctx.Err() != nil {
+				return
+			}
+			now := time.Now()
+			for _, r := range res {
+				ev := WatchEvent{Path: r.Path, Time: now, Err: r.Err}
+				v := strings.TrimRight(string(r.Data), "\x00")
+				last, known := prev[r.Path]
+				if r.Err == nil {
+					prev[r.Path] = v
+					if !known || v == last {
+						continue
+					}
+					ev.Value, ev.Previous = v, last
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, func() {
+		cancel()
+		<-done
+	}
+}
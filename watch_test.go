@@ -0,0 +1,66 @@
+package ownet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	const path = "/3A.BEE71B000000/sensed.A"
+	s := newFakeServer(t, map[string]string{path: "0"})
+	ow := New(s.addr())
+
+	events, stop := ow.Watch(path, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	s.mu.Lock()
+	s.files[path] = "1"
+	s.mu.Unlock()
+
+	ev := <-events
+	if ev.Err != nil || ev.Value != "1" || ev.Previous != "0" || ev.Path != path {
+		t.Errorf("change: got %+v", ev)
+	}
+
+	s.mu.Lock()
+	delete(s.files, path)
+	s.mu.Unlock()
+	ev = <-events
+	if !errors.Is(ev.Err, ErrNotFound) {
+		t.Errorf("error: got %+v", ev)
+	}
+
+	stop()
+	for range events {
+	}
+}
+
+func TestWatchAll(t *testing.T) {
+	paths := []string{"/3A.BEE71B000000/sensed.A", "/3A.BEE71B000000/sensed.B", "/29.000000000001/sensed.0"}
+	s := newFakeServer(t, map[string]string{paths[0]: "0", paths[1]: "0", paths[2]: "1"})
+	ow := New(s.addr(), WithPersistent())
+	defer ow.Close()
+
+	events, stop := ow.WatchAll(paths, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	s.mu.Lock()
+	s.files[paths[1]] = "1"
+	s.files[paths[2]] = "0"
+	s.mu.Unlock()
+
+	for _, want := range []WatchEvent{
+		{Path: paths[1], Value: "1", Previous: "0"},
+		{Path: paths[2], Value: "0", Previous: "1"},
+	} {
+		ev := <-events
+		if ev.Err != nil || ev.Path != want.Path || ev.Value != want.Value || ev.Previous != want.Previous {
+			t.Errorf("got %+v, want %+v", ev, want)
+		}
+	}
+	stop()
+	for range events {
+	}
+	if n := s.dialCount(); n != 1 {
+		t.Errorf("dialed %d connections, want 1", n)
+	}
+}